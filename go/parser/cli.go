@@ -0,0 +1,470 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rosen/go-parser/parser"
+)
+
+// subcommands lists the operations available as `goparser <subcommand>`
+var subcommands = map[string]string{
+	"parse":      "List the symbols declared in a Go file",
+	"outline":    "Show the symbol tree of a Go file or package directory",
+	"get":        "Print the source of one symbol",
+	"skeleton":   "Print a file with function bodies elided",
+	"context":    "Print a symbol with the signatures of the package declarations it uses",
+	"edit":       "Replace a symbol with new content",
+	"insert":     "Insert new content before or after a symbol",
+	"delete":     "Delete a symbol",
+	"doc":        "Replace, add or remove the doc comment of a symbol",
+	"rename":     "Rename a symbol and every reference to it in its package and tests",
+	"create":     "Create a Go file in the package of its directory",
+	"move":       "Move a declaration to another file of the same package",
+	"references": "List the references to a symbol across the module",
+}
+
+// cli holds the flags shared by every subcommand
+type cli struct {
+	flags     *flag.FlagSet
+	format    string
+	logLevel  string
+	logFormat string
+}
+
+func newCLI(name string) *cli {
+	c := &cli{flags: flag.NewFlagSet("goparser "+name, flag.ContinueOnError)}
+	c.flags.SetOutput(os.Stderr)
+	c.flags.StringVar(&c.format, "format", "json", "Output format: json or text")
+	c.flags.StringVar(&c.logLevel, "log-level", "warn", "Minimum level of diagnostics written to stderr: debug, info, warn or error")
+	c.flags.StringVar(&c.logFormat, "log-format", "text", "Format of diagnostics written to stderr: text or json")
+	c.flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: goparser %s [flags] FILE\n\n%s\n\nflags:\n", name, subcommands[name])
+		c.flags.PrintDefaults()
+	}
+	return c
+}
+
+// contentFlags registers the flags that supply new source for an edit
+func contentFlags(fs *flag.FlagSet) (content, file *string) {
+	content = fs.String("content", "", "New source code")
+	file = fs.String("content-file", "", "File holding the new source code ('-' for stdin, the default)")
+	return content, file
+}
+
+// readContent returns the new source from --content, --content-file or stdin
+func readContent(content, file string) (string, error) {
+	if content != "" {
+		if file != "" {
+			return "", errors.New("--content and --content-file are mutually exclusive")
+		}
+		return content, nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if file == "" || file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read content: %v", err)
+	}
+	return string(data), nil
+}
+
+// printUsage lists the subcommands on stderr
+func printUsage() {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "usage: goparser <subcommand> [flags] FILE")
+	fmt.Fprintln(os.Stderr, "       goparser -input - | -serve | -lsp | -mcp")
+	fmt.Fprintln(os.Stderr, "\nsubcommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, subcommands[name])
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'goparser <subcommand> -h' for the flags of a subcommand.")
+}
+
+// runCLI runs a subcommand and returns the process exit code
+func runCLI(name string, args []string) int {
+	c := newCLI(name)
+	fs := c.flags
+
+	var (
+		file, symbol, relativeTo, position, newName, dir, deprecated, anchor, stmtPath, pkg, dest       string
+		depth, budget, width                                                                            int
+		allowGenerated, includeTests, allVariants, signatureOnly, fuzzy, checkImplementations, bodyOnly bool
+		removeDoc, godoc, externalTest, overwrite, withMethods, allowMismatch                           bool
+		goos, goarch, tags, keep                                                                        string
+		content, contentFile                                                                            *string
+	)
+	fs.StringVar(&file, "file", "", "Path to the Go file (may also be given as the first argument)")
+	switch name {
+	case "parse", "outline":
+		fs.IntVar(&depth, "depth", 0, "Levels of local symbols to report inside function bodies")
+		if name == "outline" {
+			fs.StringVar(&dir, "dir", "", "Package directory to outline instead of a single file")
+			fs.BoolVar(&includeTests, "tests", false, "Include _test.go files")
+			fs.StringVar(&goos, "goos", "", "Target operating system for build constraints")
+			fs.StringVar(&goarch, "goarch", "", "Target architecture for build constraints")
+			fs.StringVar(&tags, "tags", "", "Comma-separated build tags")
+			fs.BoolVar(&allVariants, "all-variants", false, "Include files excluded by build constraints")
+		}
+	case "get":
+		fs.StringVar(&symbol, "symbol", "", "Symbol to select: Name or Type.Member")
+		fs.BoolVar(&signatureOnly, "signature", false, "Elide the function body and print just the signature")
+	case "skeleton":
+		fs.StringVar(&keep, "keep", "", "Comma-separated symbols whose bodies are kept in full")
+	case "context":
+		fs.StringVar(&symbol, "symbol", "", "Target symbol: Name or Type.Method")
+		fs.IntVar(&budget, "budget", 0, "Maximum total size of the sources in bytes (0 for no limit)")
+	case "delete":
+		fs.StringVar(&symbol, "symbol", "", "Symbol to delete: Name or Type.Method")
+	case "edit":
+		fs.StringVar(&symbol, "symbol", "", "Symbol to replace")
+		fs.BoolVar(&bodyOnly, "body", false, "Replace only the function body; the content holds just the statements")
+		content, contentFile = contentFlags(fs)
+	case "insert":
+		fs.StringVar(&symbol, "symbol", "", "Name of the inserted symbol (defaults to the anchor)")
+		fs.StringVar(&position, "position", "after", "Where to insert: before or after the anchor, start, after-imports, end, first-method-of:T or last-method-of:T")
+		fs.StringVar(&relativeTo, "relative-to", "", "Anchor symbol for the insertion")
+		content, contentFile = contentFlags(fs)
+	case "doc":
+		fs.StringVar(&symbol, "symbol", "", "Symbol to document: Name or Type.Member")
+		fs.BoolVar(&removeDoc, "remove", false, "Remove the doc comment instead of reading new text")
+		fs.IntVar(&width, "width", 0, "Wrap lines at this length including \"// \" (0 for 80, negative to disable)")
+		fs.BoolVar(&godoc, "godoc", false, "Require the comment to start with the symbol name")
+		fs.StringVar(&deprecated, "deprecated", "", "Append a Deprecated: paragraph with this text")
+		content, contentFile = contentFlags(fs)
+	case "create":
+		fs.StringVar(&pkg, "package", "", "Package name (inferred from the other files in the directory by default)")
+		fs.BoolVar(&externalTest, "external-test", false, "Use the external test package for a _test.go file")
+		fs.BoolVar(&overwrite, "overwrite", false, "Replace the file if it already exists")
+		content, contentFile = contentFlags(fs)
+	case "rename":
+		fs.StringVar(&symbol, "symbol", "", "Symbol to rename: Name or Type.Member")
+		fs.StringVar(&newName, "to", "", "New name for the symbol")
+		fs.BoolVar(&allowGenerated, "allow-generated", false, "Allow rewriting references in generated files")
+	case "references":
+		fs.StringVar(&symbol, "symbol", "", "Symbol to find: Name or Type.Member")
+		fs.StringVar(&dir, "root", "", "Directory to search (defaults to the module root)")
+	case "move":
+		fs.StringVar(&symbol, "symbol", "", "Symbol to move: Name or Type.Method")
+		fs.StringVar(&dest, "to", "", "File to move the symbol to, created when missing")
+		fs.BoolVar(&withMethods, "with-methods", false, "Move the methods the file declares on a moved type along with it")
+		fs.BoolVar(&allowGenerated, "allow-generated", false, "Allow moving out of or into generated files")
+		fs.BoolVar(&allowMismatch, "allow-constraint-mismatch", false, "Allow moving between files built under different constraints")
+	}
+	if name == "edit" || name == "insert" || name == "delete" || name == "doc" {
+		fs.BoolVar(&allowGenerated, "allow-generated", false, "Allow editing generated files")
+		fs.BoolVar(&fuzzy, "fuzzy", false, "Edit the only close match when the target symbol is misspelled or miscased")
+	}
+	if name == "edit" || name == "insert" || name == "delete" {
+		fs.StringVar(&anchor, "anchor", "", "Edit the statement of the --symbol function containing this source snippet")
+		fs.StringVar(&stmtPath, "statement", "", "Edit the statement of the --symbol function at this path, such as 2.1.1")
+		fs.BoolVar(&checkImplementations, "check-implementations", false, "Report package types that stop satisfying an edited interface")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return exitCodes[parser.CodeInvalidRequest]
+	}
+	if c.format != "json" && c.format != "text" {
+		return c.fail(codedError(parser.CodeInvalidRequest, fmt.Errorf("invalid format %q: must be json or text", c.format)))
+	}
+	logger, err := newLogger(os.Stderr, c.logLevel, c.logFormat)
+	if err != nil {
+		return c.fail(codedError(parser.CodeInvalidRequest, err))
+	}
+	slog.SetDefault(logger)
+	parser.SetLogger(logger)
+
+	if fs.NArg() > 1 || (fs.NArg() == 1 && file != "") {
+		return c.fail(codedError(parser.CodeInvalidRequest, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))))
+	}
+	if file == "" {
+		file = fs.Arg(0)
+	}
+	if name == "outline" && dir == "" {
+		if info, err := os.Stat(file); err == nil && info.IsDir() {
+			dir, file = file, ""
+		}
+	}
+	if file == "" && dir == "" {
+		return c.fail(codedError(parser.CodeInvalidRequest, errors.New("file path is required")))
+	}
+
+	switch name {
+	case "parse", "outline":
+		opts := parser.ParseOptions{Depth: depth}
+		if dir != "" {
+			pkgOpts := parser.PackageOptions{
+				ParseOptions: opts,
+				IncludeTests: includeTests,
+				GOOS:         goos,
+				GOARCH:       goarch,
+				AllVariants:  allVariants,
+			}
+			if tags != "" {
+				pkgOpts.Tags = strings.Split(tags, ",")
+			}
+			result, err := execute(Command{Operation: "parse-package", Dir: dir, Package: &pkgOpts})
+			if err != nil {
+				return c.fail(err)
+			}
+			pkg := result.(parser.PackageResult)
+			return c.output(pkg.Merged, func(w io.Writer) {
+				fmt.Fprintf(w, "package %s\n", pkg.Package)
+				writeOutline(w, pkg.Merged, "")
+			})
+		}
+		result, err := execute(Command{Operation: "parse", File: file, Options: &opts})
+		if err != nil {
+			return c.fail(err)
+		}
+		parsed := result.(parser.ParseResult)
+		var out interface{} = parsed
+		if name == "outline" {
+			out = parsed.Symbols
+		}
+		src, _ := os.ReadFile(file)
+		lines := newLineIndex(src)
+		return c.output(out, func(w io.Writer) {
+			if parsed.File != nil {
+				fmt.Fprintf(w, "package %s\n", parsed.File.Package)
+			}
+			if name == "outline" {
+				writeOutline(w, parsed.Symbols, "")
+				return
+			}
+			writeSymbols(w, lines, parsed.Symbols, "")
+		})
+
+	case "get":
+		if symbol == "" {
+			return c.fail(codedError(parser.CodeInvalidRequest, errors.New("--symbol is required")))
+		}
+		result, err := execute(Command{Operation: "get", File: file, Get: &parser.GetRequest{Path: file, Symbol: symbol, SignatureOnly: signatureOnly}})
+		if err != nil {
+			return c.fail(err)
+		}
+		return c.output(result, func(w io.Writer) {
+			fmt.Fprintln(w, result.(parser.GetResult).Source)
+		})
+
+	case "skeleton":
+		req := &parser.SkeletonRequest{Path: file}
+		if keep != "" {
+			req.Keep = strings.Split(keep, ",")
+		}
+		result, err := execute(Command{Operation: "skeleton", File: file, Skeleton: req})
+		if err != nil {
+			return c.fail(err)
+		}
+		return c.output(result, func(w io.Writer) {
+			fmt.Fprint(w, result.(parser.SkeletonResult).Content)
+		})
+
+	case "context":
+		result, err := execute(Command{Operation: "context", File: file, Context: &parser.ContextRequest{Path: file, Symbol: symbol, Budget: budget}})
+		if err != nil {
+			return c.fail(err)
+		}
+		bundle := result.(parser.ContextResult)
+		return c.output(bundle, func(w io.Writer) {
+			for i, e := range bundle.Entries {
+				if i > 0 {
+					fmt.Fprintln(w)
+				}
+				fmt.Fprintf(w, "// %s:%d\n%s\n", e.Path, e.StartLine, e.Source)
+			}
+			if len(bundle.Omitted) > 0 {
+				fmt.Fprintf(w, "\n// omitted to fit the budget: %s\n", strings.Join(bundle.Omitted, ", "))
+			}
+		})
+
+	case "rename":
+		result, err := execute(Command{Operation: "rename", File: file, Rename: &parser.RenameRequest{Path: file, Symbol: symbol, NewName: newName, AllowGenerated: allowGenerated}})
+		if err != nil {
+			return c.fail(err)
+		}
+		renamed := result.(parser.RenameResult)
+		return c.output(renamed, func(w io.Writer) {
+			if len(renamed.Renamed) > 0 {
+				fmt.Fprintf(os.Stderr, "goparser: also renamed %s\n", strings.Join(renamed.Renamed, ", "))
+			}
+			for _, f := range renamed.Files {
+				fmt.Fprint(w, f.Diff)
+			}
+		})
+
+	case "create":
+		req := &parser.CreateRequest{Path: file, Package: pkg, ExternalTest: externalTest, Overwrite: overwrite}
+		if req.Content, err = readContent(*content, *contentFile); err != nil {
+			return c.fail(codedError(parser.CodeInvalidRequest, err))
+		}
+		before, _ := os.ReadFile(file)
+		result, err := execute(Command{Operation: "create", File: file, Create: req})
+		if err != nil {
+			return c.fail(err)
+		}
+		return c.editOutput(file, before, result)
+	case "references":
+		result, err := execute(Command{Operation: "references", File: file, References: &parser.ReferencesRequest{Path: file, Symbol: symbol, Root: dir}})
+		if err != nil {
+			return c.fail(err)
+		}
+		refs := result.(parser.ReferencesResult)
+		return c.output(refs, func(w io.Writer) {
+			for _, ref := range refs.References {
+				fmt.Fprintf(w, "%s:%d:%d\t%s\t%s\n", ref.Path, ref.Line, ref.Column, ref.Kind, ref.Enclosing)
+			}
+			if len(refs.Incomplete) > 0 {
+				fmt.Fprintf(os.Stderr, "goparser: could not load, references may be missing: %s\n", strings.Join(refs.Incomplete, ", "))
+			}
+		})
+
+	case "move":
+		result, err := execute(Command{Operation: "move", File: file, Move: &parser.MoveRequest{
+			Path: file, Symbol: symbol, Destination: dest, WithMethods: withMethods, AllowGenerated: allowGenerated,
+			AllowConstraintMismatch: allowMismatch,
+		}})
+		if err != nil {
+			return c.fail(err)
+		}
+		moved := result.(parser.MoveResult)
+		return c.output(moved, func(w io.Writer) {
+			fmt.Fprintf(os.Stderr, "goparser: moved %s\n", strings.Join(moved.Moved, ", "))
+			if len(moved.UnresolvedImports) > 0 {
+				fmt.Fprintf(os.Stderr, "goparser: no import found for: %s\n", strings.Join(moved.UnresolvedImports, ", "))
+			}
+			for _, f := range moved.Files {
+				fmt.Fprint(w, f.Diff)
+			}
+		})
+	}
+
+	req := &parser.EditRequest{Path: file, EditType: name, Symbol: symbol, AllowGenerated: allowGenerated, FuzzyMatch: fuzzy, CheckImplementations: checkImplementations}
+	if name == "edit" {
+		req.EditType = "replace"
+		if bodyOnly {
+			req.EditType = "replace-body"
+		}
+	}
+	if name == "insert" {
+		if req.Symbol == "" {
+			req.Symbol = relativeTo
+		}
+		req.Insert = &parser.InsertConfig{Position: position, RelativeToSymbol: relativeTo}
+	}
+	if anchor != "" || stmtPath != "" {
+		// Statement edits target the body of --symbol rather than a declaration
+		req.EditType = name + "-statement"
+		if name == "edit" {
+			req.EditType = "replace-statement"
+		}
+		req.Insert = nil
+		req.Statement = &parser.StatementConfig{Anchor: anchor, Path: stmtPath}
+		if name == "insert" {
+			req.Statement.Position = position
+		}
+	}
+	if name == "doc" {
+		req.EditType = "set-doc"
+		req.Doc = &parser.DocConfig{Width: width, Godoc: godoc, Deprecated: deprecated}
+	}
+	if content != nil && !removeDoc {
+		if req.Content, err = readContent(*content, *contentFile); err != nil {
+			return c.fail(codedError(parser.CodeInvalidRequest, err))
+		}
+	}
+	before, _ := os.ReadFile(file)
+	result, err := execute(Command{Operation: "edit", File: file, Edit: req})
+	if err != nil {
+		return c.fail(err)
+	}
+	return c.editOutput(file, before, result)
+}
+
+// output writes v as JSON, or calls text to render it for humans
+func (c *cli) output(v interface{}, text func(w io.Writer)) int {
+	if c.format == "text" {
+		text(stdout)
+		return 0
+	}
+	writeJSON(v)
+	return 0
+}
+
+// editOutput reports a successful edit, as a unified diff in text mode
+func (c *cli) editOutput(path string, before []byte, result interface{}) int {
+	edit := result.(parser.EditResult)
+	return c.output(edit, func(w io.Writer) {
+		if edit.ResolvedSymbol != "" {
+			fmt.Fprintf(os.Stderr, "goparser: edited %s\n", edit.ResolvedSymbol)
+		}
+		if len(edit.BrokenImplementations) > 0 {
+			fmt.Fprintf(os.Stderr, "goparser: no longer satisfy the interface: %s\n", strings.Join(edit.BrokenImplementations, ", "))
+		}
+		if len(edit.UnresolvedImports) > 0 {
+			fmt.Fprintf(os.Stderr, "goparser: no import found for: %s\n", strings.Join(edit.UnresolvedImports, ", "))
+		}
+		fmt.Fprint(w, parser.UnifiedDiff(path, string(before), edit.Content))
+	})
+}
+
+// fail reports err on stderr in the selected format and returns its exit code
+func (c *cli) fail(err error) int {
+	if c.format == "text" {
+		fmt.Fprintf(os.Stderr, "goparser: %s\n", codedError(parser.CodeInternal, err).Message)
+	} else {
+		writeError(err)
+	}
+	return exitCode(err)
+}
+
+// writeOutline prints symbols as an indented tree
+func writeOutline(w io.Writer, symbols []parser.Symbol, indent string) {
+	for _, s := range symbols {
+		fmt.Fprintf(w, "%s%s\n", indent, symbolLabel(s))
+		writeOutline(w, s.Children, indent+"  ")
+	}
+}
+
+// writeSymbols prints one symbol per line with its line range
+func writeSymbols(w io.Writer, lines *lineIndex, symbols []parser.Symbol, indent string) {
+	for _, s := range symbols {
+		start := lines.position(s.Start).Line + 1
+		end := lines.position(s.End).Line + 1
+		fmt.Fprintf(w, "%5d-%-5d %s%s\n", start, end, indent, symbolLabel(s))
+		writeSymbols(w, lines, s.Children, indent+"  ")
+	}
+}
+
+// symbolLabel describes a symbol as "kind name", qualifying methods with
+// their receiver
+func symbolLabel(s parser.Symbol) string {
+	name := s.Name
+	if s.Receiver != "" {
+		name = "(" + s.Receiver + ")." + name
+	}
+	label := s.Kind + " " + name
+	if s.File != "" {
+		label += "  [" + s.File + "]"
+	}
+	return label
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLI(t *testing.T) {
+	initial := `package test
+
+// Process handles data
+func Process() error {
+	return nil
+}
+
+func Cleanup() {}
+`
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantCode int
+		want     []string // substrings of stdout
+		wantFile []string // substrings of the file afterwards
+	}{
+		{
+			name: "parse json",
+			args: []string{"parse"},
+			want: []string{`"name":"Process"`},
+		},
+		{
+			name: "parse text",
+			args: []string{"parse", "--format=text"},
+			want: []string{"package test", "4-6", "function Process"},
+		},
+		{
+			name: "outline text",
+			args: []string{"outline", "--format", "text"},
+			want: []string{"function Process\nfunction Cleanup\n"},
+		},
+		{
+			name: "get",
+			args: []string{"get", "--format=text", "--symbol", "Cleanup"},
+			want: []string{"func Cleanup() {}"},
+		},
+		{
+			name: "get signature",
+			args: []string{"get", "--format=text", "--signature", "--symbol", "Process"},
+			want: []string{"// Process handles data\nfunc Process() error\n"},
+		},
+		{
+			name: "skeleton",
+			args: []string{"skeleton", "--format=text", "--keep", "Cleanup"},
+			want: []string{"func Process() error { ... }\n\nfunc Cleanup() {}\n"},
+		},
+		{
+			name:     "edit from stdin",
+			args:     []string{"edit", "--format=text", "--symbol", "Cleanup"},
+			stdin:    "func Cleanup() error { return nil }",
+			want:     []string{"-func Cleanup() {}", "+func Cleanup() error { return nil }"},
+			wantFile: []string{"func Cleanup() error"},
+		},
+		{
+			name:     "insert",
+			args:     []string{"insert", "--position", "before", "--relative-to", "Process", "--content", "func Validate() {}"},
+			want:     []string{`"Success":true`},
+			wantFile: []string{"func Validate() {}\n\n// Process handles data"},
+		},
+		{
+			name:     "delete",
+			args:     []string{"delete", "--format=text", "--symbol=Cleanup"},
+			want:     []string{"-func Cleanup() {}"},
+			wantFile: []string{"return nil\n}\n"},
+		},
+		{
+			name:     "rename",
+			args:     []string{"rename", "--format=text", "--symbol", "Process", "--to", "Handle"},
+			want:     []string{"+func Handle() error {"},
+			wantFile: []string{"func Handle() error"},
+		},
+		{
+			name:     "doc",
+			args:     []string{"doc", "--format=text", "--symbol", "Cleanup", "--deprecated", "use Close."},
+			stdin:    "Cleanup releases resources.",
+			want:     []string{"+// Cleanup releases resources.", "+// Deprecated: use Close."},
+			wantFile: []string{"//\n// Deprecated: use Close.\nfunc Cleanup() {}"},
+		},
+		{
+			name:     "doc remove",
+			args:     []string{"doc", "--remove", "--symbol", "Process"},
+			want:     []string{`"Success":true`},
+			wantFile: []string{"package test\n\nfunc Process() error {"},
+		},
+		{
+			name:     "insert at end",
+			args:     []string{"insert", "--position", "end", "--content", "func Last() {}"},
+			want:     []string{`"Success":true`},
+			wantFile: []string{"func Cleanup() {}\n\nfunc Last() {}\n"},
+		},
+		{
+			name:     "insert statement",
+			args:     []string{"insert", "--symbol", "Process", "--anchor", "return nil", "--position", "before", "--content", "log.Print()"},
+			want:     []string{`"Success":true`},
+			wantFile: []string{"\tlog.Print()\n\treturn nil\n"},
+		},
+		{
+			name:     "anchor and statement path",
+			args:     []string{"delete", "--symbol", "Process", "--anchor", "return nil", "--statement", "1"},
+			wantCode: exitCodes["INVALID_REQUEST"],
+		},
+		{
+			name:     "create over existing file",
+			args:     []string{"create", "--content", "func X() {}"},
+			wantCode: exitCodes["FILE_EXISTS"],
+			wantFile: []string{"func Cleanup() {}"},
+		},
+		{
+			name:     "create with overwrite",
+			args:     []string{"create", "--overwrite", "--format=text", "--content", "func X() { fmt.Println() }"},
+			want:     []string{"+func X() { fmt.Println() }"},
+			wantFile: []string{"package test\n\nimport (\n\t\"fmt\"\n)\n\nfunc X()"},
+		},
+		{
+			name: "references",
+			args: []string{"references", "--format=text", "--symbol", "Cleanup"},
+		},
+		{
+			name: "references json",
+			args: []string{"references", "--symbol", "Process"},
+			want: []string{`"Symbol":"Process"`, `"Kind":"declaration"`, `"References":[]`},
+		},
+		{
+			name:     "missing symbol",
+			args:     []string{"delete", "--symbol", "Missing"},
+			wantCode: exitCodes["SYMBOL_NOT_FOUND"],
+		},
+		{
+			name:     "invalid format",
+			args:     []string{"parse", "--format=yaml"},
+			wantCode: exitCodes["INVALID_REQUEST"],
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"parse", "--symbol", "Process"},
+			wantCode: exitCodes["INVALID_REQUEST"],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.go")
+			if err := os.WriteFile(path, []byte(initial), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			stdout, stderr, code := runMain(t, tt.stdin, append(tt.args, path)...)
+			if code != tt.wantCode {
+				t.Fatalf("Exit code = %d, want %d (stderr: %s)", code, tt.wantCode, stderr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("stdout missing %q:\n%s", want, stdout)
+				}
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.wantFile {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("File missing %q:\n%s", want, content)
+				}
+			}
+		})
+	}
+}
+
+func TestCLIMove(t *testing.T) {
+	dir := t.TempDir()
+	path, dest := filepath.Join(dir, "test.go"), filepath.Join(dir, "cleanup.go")
+	src := "package test\n\nimport \"os\"\n\nfunc Process() {}\n\n// Cleanup removes the temp dir.\nfunc Cleanup() { os.RemoveAll(os.TempDir()) }\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code := runMain(t, "", "move", "--format=text", "--symbol", "Cleanup", "--to", dest, path)
+	if code != 0 {
+		t.Fatalf("Exit code = %d (stderr: %s)", code, stderr)
+	}
+	if !strings.Contains(stdout, "-func Cleanup()") || !strings.Contains(stdout, "+func Cleanup()") {
+		t.Errorf("stdout missing the diffs of both files:\n%s", stdout)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "package test\n\nfunc Process() {}\n" {
+		t.Errorf("Source = %q", got)
+	}
+	want := "package test\n\nimport (\n\t\"os\"\n)\n\n// Cleanup removes the temp dir.\nfunc Cleanup() { os.RemoveAll(os.TempDir()) }\n"
+	if got, _ := os.ReadFile(dest); string(got) != want {
+		t.Errorf("Destination = %q, want %q", got, want)
+	}
+}
+
+func TestCLIUsage(t *testing.T) {
+	stdout, stderr, code := runMain(t, "")
+	if code != exitCodes["INVALID_REQUEST"] || stdout != "" {
+		t.Errorf("Exit code = %d, stdout = %q", code, stdout)
+	}
+	if !strings.Contains(stderr, "usage: goparser <subcommand>") {
+		t.Errorf("No usage on stderr:\n%s", stderr)
+	}
+}
@@ -0,0 +1,757 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/rosen/go-parser/parser"
+)
+
+// LSP symbol kinds, see the SymbolKind enumeration of the specification
+const (
+	lspKindClass     = 5
+	lspKindMethod    = 6
+	lspKindField     = 8
+	lspKindInterface = 11
+	lspKindFunction  = 12
+	lspKindVariable  = 13
+	lspKindConstant  = 14
+	lspKindStruct    = 23
+)
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspTextDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text,omitempty"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail,omitempty"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children,omitempty"`
+}
+
+type lspSymbolInformation struct {
+	Name          string      `json:"name"`
+	Kind          int         `json:"kind"`
+	Location      lspLocation `json:"location"`
+	ContainerName string      `json:"containerName,omitempty"`
+}
+
+type lspFoldingRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Kind      string `json:"kind,omitempty"`
+}
+
+type lspSelectionRange struct {
+	Range  lspRange           `json:"range"`
+	Parent *lspSelectionRange `json:"parent,omitempty"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspWorkspaceEdit struct {
+	Changes map[string][]lspTextEdit `json:"changes"`
+}
+
+type lspCodeAction struct {
+	Title string            `json:"title"`
+	Kind  string            `json:"kind"`
+	Edit  *lspWorkspaceEdit `json:"edit,omitempty"`
+}
+
+// lspConn reads and writes JSON-RPC 2.0 messages framed with
+// Content-Length headers as required by the Language Server Protocol
+type lspConn struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// read returns the body of the next message
+func (c *lspConn) read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %v", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("missing Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// write sends v as a single framed message
+func (c *lspConn) write(v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+// lspServer answers symbol queries and edit code actions from the content
+// of the documents the client has opened, falling back to the file system
+// for the rest of the workspace
+type lspServer struct {
+	conn     *lspConn
+	root     string
+	docs     map[string][]byte
+	shutdown bool
+}
+
+// serveLSP runs a language server over r and w until the client sends exit.
+// Messages are handled in order so that document changes are applied before
+// the queries that follow them.
+func serveLSP(r io.Reader, w io.Writer) error {
+	s := &lspServer{
+		conn: &lspConn{r: bufio.NewReader(r), w: w},
+		docs: make(map[string][]byte),
+	}
+	for {
+		body, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.conn.write(rpcResponse{
+				JSONRPC: "2.0",
+				ID:      rpcNullID,
+				Error:   &rpcError{Code: rpcParseError, Message: fmt.Sprintf("invalid JSON: %v", err)},
+			})
+			continue
+		}
+		// Responses to server-initiated requests carry no method
+		if req.Method == "" {
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit received before shutdown")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(req)
+		if len(req.ID) == 0 {
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+		if rpcErr == nil {
+			resp.Result = result
+			if result == nil {
+				resp.Result = json.RawMessage("null")
+			}
+		}
+		if err := s.conn.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a single request or notification
+func (s *lspServer) handle(req rpcRequest) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			RootURI          string `json:"rootUri"`
+			WorkspaceFolders []struct {
+				URI string `json:"uri"`
+			} `json:"workspaceFolders"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if params.RootURI != "" {
+			s.root = uriToPath(params.RootURI)
+		} else if len(params.WorkspaceFolders) > 0 {
+			s.root = uriToPath(params.WorkspaceFolders[0].URI)
+		}
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":        1, // Full content on every change
+				"documentSymbolProvider":  true,
+				"workspaceSymbolProvider": true,
+				"foldingRangeProvider":    true,
+				"selectionRangeProvider":  true,
+				"codeActionProvider":      true,
+			},
+			"serverInfo": map[string]string{"name": "goparser"},
+		}, nil
+
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.docs[params.TextDocument.URI] = []byte(params.TextDocument.Text)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   lspTextDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		// Full sync sends the whole document as the last change
+		if n := len(params.ContentChanges); n > 0 {
+			s.docs[params.TextDocument.URI] = []byte(params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, nil
+
+	case "textDocument/documentSymbol":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.documentSymbols(params.TextDocument.URI)
+
+	case "workspace/symbol":
+		var params struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.workspaceSymbols(params.Query), nil
+
+	case "textDocument/foldingRange":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.foldingRanges(params.TextDocument.URI)
+
+	case "textDocument/selectionRange":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+			Positions    []lspPosition   `json:"positions"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.selectionRanges(params.TextDocument.URI, params.Positions)
+
+	case "textDocument/codeAction":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+			Range        lspRange        `json:"range"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.codeActions(params.TextDocument.URI, params.Range)
+	}
+
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unsupported method: %s", req.Method)}
+}
+
+// source returns the content of a document, preferring the open buffer
+func (s *lspServer) source(uri string) ([]byte, error) {
+	if src, ok := s.docs[uri]; ok {
+		return src, nil
+	}
+	return os.ReadFile(uriToPath(uri))
+}
+
+// parse returns the symbols of a document with a line index for positions
+func (s *lspServer) parse(uri string) ([]parser.Symbol, *lineIndex, *rpcError) {
+	src, err := s.source(uri)
+	if err != nil {
+		return nil, nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("failed to read document: %v", err)}
+	}
+	result, err := parser.ParseSource(uriToPath(uri), src, parser.ParseOptions{Depth: 1})
+	if err != nil {
+		return nil, nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("failed to parse document: %v", err)}
+	}
+	return result.Symbols, newLineIndex(src), nil
+}
+
+func (s *lspServer) documentSymbols(uri string) (interface{}, *rpcError) {
+	symbols, lines, rpcErr := s.parse(uri)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	var convert func(symbols []parser.Symbol) []lspDocumentSymbol
+	convert = func(symbols []parser.Symbol) []lspDocumentSymbol {
+		result := []lspDocumentSymbol{}
+		for _, symbol := range symbols {
+			result = append(result, lspDocumentSymbol{
+				Name:           symbol.Name,
+				Detail:         symbolDetail(symbol),
+				Kind:           lspSymbolKind(symbol),
+				Range:          lines.rangeOf(symbol.Start, symbol.End),
+				SelectionRange: lines.nameRange(symbol),
+				Children:       convert(symbol.Children),
+			})
+		}
+		return result
+	}
+	return convert(symbols), nil
+}
+
+// workspaceSymbols searches open documents and the Go files below the
+// workspace root for symbols whose name contains the query, ignoring case
+func (s *lspServer) workspaceSymbols(query string) []lspSymbolInformation {
+	uris := make(map[string]bool)
+	for uri := range s.docs {
+		uris[uri] = true
+	}
+	if s.root != "" {
+		filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := d.Name()
+			if d.IsDir() {
+				if path != s.root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+					name == "vendor" || name == "testdata" || name == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(name, ".go") {
+				uris[pathToURI(path)] = true
+			}
+			return nil
+		})
+	}
+
+	sorted := make([]string, 0, len(uris))
+	for uri := range uris {
+		sorted = append(sorted, uri)
+	}
+	sort.Strings(sorted)
+
+	query = strings.ToLower(query)
+	result := []lspSymbolInformation{}
+	for _, uri := range sorted {
+		symbols, lines, rpcErr := s.parse(uri)
+		if rpcErr != nil {
+			continue
+		}
+		var collect func(symbols []parser.Symbol, container string)
+		collect = func(symbols []parser.Symbol, container string) {
+			for _, symbol := range symbols {
+				if strings.Contains(strings.ToLower(symbol.Name), query) {
+					result = append(result, lspSymbolInformation{
+						Name:          symbol.Name,
+						Kind:          lspSymbolKind(symbol),
+						Location:      lspLocation{URI: uri, Range: lines.rangeOf(symbol.Start, symbol.End)},
+						ContainerName: container,
+					})
+				}
+				collect(symbol.Children, symbol.Name)
+			}
+		}
+		collect(symbols, "")
+	}
+	return result
+}
+
+func (s *lspServer) foldingRanges(uri string) (interface{}, *rpcError) {
+	src, err := s.source(uri)
+	if err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("failed to read document: %v", err)}
+	}
+	result, err := parser.ParseSource(uriToPath(uri), src, parser.ParseOptions{Depth: 1})
+	if err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("failed to parse document: %v", err)}
+	}
+	lines := newLineIndex(src)
+
+	ranges := []lspFoldingRange{}
+	if imports := result.File.Imports; len(imports) > 0 {
+		start := lines.position(imports[0].Start).Line
+		end := lines.position(imports[len(imports)-1].End).Line
+		if end > start {
+			ranges = append(ranges, lspFoldingRange{StartLine: start, EndLine: end, Kind: "imports"})
+		}
+	}
+	var collect func(symbols []parser.Symbol)
+	collect = func(symbols []parser.Symbol) {
+		for _, symbol := range symbols {
+			r := lines.rangeOf(symbol.Start, symbol.End)
+			if r.End.Line > r.Start.Line {
+				ranges = append(ranges, lspFoldingRange{StartLine: r.Start.Line, EndLine: r.End.Line})
+			}
+			collect(symbol.Children)
+		}
+	}
+	collect(result.Symbols)
+	return ranges, nil
+}
+
+// selectionRanges returns, for each position, the chain of syntax nodes
+// enclosing it from the innermost outwards
+func (s *lspServer) selectionRanges(uri string, positions []lspPosition) (interface{}, *rpcError) {
+	src, err := s.source(uri)
+	if err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("failed to read document: %v", err)}
+	}
+	fset := token.NewFileSet()
+	// Partial syntax trees of files with errors are still useful here
+	file, _ := goparser.ParseFile(fset, uriToPath(uri), src, goparser.ParseComments)
+	if file == nil {
+		return nil, &rpcError{Code: rpcServerError, Message: "failed to parse document"}
+	}
+	tokFile := fset.File(file.Pos())
+	lines := newLineIndex(src)
+
+	result := []lspSelectionRange{}
+	for _, pos := range positions {
+		offset := lines.offset(pos)
+		var chain *lspSelectionRange
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n == nil {
+				return false
+			}
+			start, end := tokFile.Offset(n.Pos()), tokFile.Offset(n.End())
+			if offset < start || offset > end {
+				return false
+			}
+			r := lines.rangeOf(start, end)
+			if chain == nil || chain.Range != r {
+				chain = &lspSelectionRange{Range: r, Parent: chain}
+			}
+			return true
+		})
+		if chain == nil {
+			chain = &lspSelectionRange{Range: lspRange{Start: pos, End: pos}}
+		}
+		result = append(result, *chain)
+	}
+	return result, nil
+}
+
+// codeActions offers the symbol edit operations that need no further input
+// for the declaration or member under the requested range: deleting it and
+// removing its doc comment. Each action edits only the lines it changes.
+func (s *lspServer) codeActions(uri string, r lspRange) (interface{}, *rpcError) {
+	src, err := s.source(uri)
+	if err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("failed to read document: %v", err)}
+	}
+	path := uriToPath(uri)
+	result, err := parser.ParseSource(path, src, parser.ParseOptions{})
+	if err != nil {
+		// No actions are offered for documents that do not parse
+		return []lspCodeAction{}, nil
+	}
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, path, src, goparser.ParseComments)
+	if err != nil {
+		return []lspCodeAction{}, nil
+	}
+	lines := newLineIndex(src)
+	offset := lines.offset(r.Start)
+
+	actions := []lspCodeAction{}
+	add := func(title string, edit lspTextEdit) {
+		actions = append(actions, lspCodeAction{
+			Title: title,
+			Kind:  "refactor.rewrite",
+			Edit:  &lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: {edit}}},
+		})
+	}
+	// offer runs an edit operation on the buffer and offers the lines it
+	// changed, if any
+	offer := func(title string, req parser.EditRequest) {
+		req.Path = path
+		if edited := parser.EditSource(req, src); edited.Success && edited.Content != string(src) {
+			add(title, changedLines(lines, src, []byte(edited.Content)))
+		}
+	}
+
+	// Specs of var and const groups cannot be deleted on their own
+	grouped := make(map[int]bool)
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Lparen.IsValid() && (d.Tok == token.VAR || d.Tok == token.CONST) {
+			for _, spec := range d.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					grouped[fset.Position(name.Pos()).Offset] = true
+				}
+			}
+		}
+	}
+
+	for _, symbol := range result.Symbols {
+		if offset < symbol.Start || offset > symbol.End || grouped[symbol.Start] {
+			continue
+		}
+		name, kind := symbol.Name, symbol.Kind
+		if symbol.Receiver != "" {
+			name, kind = symbol.Receiver+"."+name, "method"
+		}
+		onMember := false
+		for _, child := range symbol.Children {
+			if offset < child.Start || offset > child.End {
+				continue
+			}
+			onMember = true
+			member := name + "." + child.Name
+			offer(fmt.Sprintf("Delete %s %s", child.Kind, member), parser.EditRequest{EditType: "delete", Symbol: member})
+			offer("Remove doc comment of "+member, parser.EditRequest{EditType: "set-doc", Symbol: member})
+		}
+		if onMember {
+			continue
+		}
+		offer(fmt.Sprintf("Delete %s %s", kind, name), parser.EditRequest{EditType: "delete", Symbol: name})
+		offer("Remove doc comment of "+name, parser.EditRequest{EditType: "set-doc", Symbol: name})
+	}
+	return actions, nil
+}
+
+// changedLines returns an edit replacing only the lines that differ between
+// src and edited
+func changedLines(lines *lineIndex, src, edited []byte) lspTextEdit {
+	prefix := 0
+	for prefix < len(src) && prefix < len(edited) && src[prefix] == edited[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(src)-prefix && suffix < len(edited)-prefix && src[len(src)-1-suffix] == edited[len(edited)-1-suffix] {
+		suffix++
+	}
+	// Widen the change to whole lines
+	for prefix > 0 && src[prefix-1] != '\n' {
+		prefix--
+	}
+	for suffix > 0 && src[len(src)-suffix-1] != '\n' {
+		suffix--
+	}
+	return lspTextEdit{
+		Range:   lines.rangeOf(prefix, len(src)-suffix),
+		NewText: string(edited[prefix : len(edited)-suffix]),
+	}
+}
+
+// lspSymbolKind maps a parser symbol kind onto an LSP SymbolKind
+func lspSymbolKind(symbol parser.Symbol) int {
+	switch symbol.Kind {
+	case "function":
+		if symbol.Receiver != "" {
+			return lspKindMethod
+		}
+		return lspKindFunction
+	case "method":
+		return lspKindMethod
+	case "struct":
+		return lspKindStruct
+	case "interface":
+		return lspKindInterface
+	case "field":
+		return lspKindField
+	case "variable":
+		return lspKindVariable
+	case "constant":
+		return lspKindConstant
+	case "closure", "subtest":
+		return lspKindFunction
+	}
+	return lspKindClass
+}
+
+// symbolDetail describes a symbol in document outlines
+func symbolDetail(symbol parser.Symbol) string {
+	if symbol.Receiver != "" {
+		return "(" + symbol.Receiver + ")"
+	}
+	return symbol.Kind
+}
+
+// invalidParams reports a request whose params could not be decoded
+func invalidParams(err error) *rpcError {
+	return &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+}
+
+// uriToPath converts a file:// URI into a local path
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	// file:///C:/dir carries a leading slash before the drive letter
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// pathToURI converts a local path into a file:// URI
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// lineIndex converts between byte offsets and LSP positions, which count
+// characters in UTF-16 code units
+type lineIndex struct {
+	src   []byte
+	lines []int // offsets of line starts
+}
+
+func newLineIndex(src []byte) *lineIndex {
+	lines := []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &lineIndex{src: src, lines: lines}
+}
+
+// position returns the LSP position of a byte offset
+func (l *lineIndex) position(offset int) lspPosition {
+	if offset > len(l.src) {
+		offset = len(l.src)
+	}
+	line := sort.Search(len(l.lines), func(i int) bool { return l.lines[i] > offset }) - 1
+	character := 0
+	for _, r := range string(l.src[l.lines[line]:offset]) {
+		character += len(utf16.Encode([]rune{r}))
+	}
+	return lspPosition{Line: line, Character: character}
+}
+
+// offset returns the byte offset of an LSP position, clamped to its line
+func (l *lineIndex) offset(pos lspPosition) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(l.lines) {
+		return len(l.src)
+	}
+	offset := l.lines[pos.Line]
+	for units := 0; units < pos.Character && offset < len(l.src) && l.src[offset] != '\n'; {
+		r, size := utf8.DecodeRune(l.src[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+func (l *lineIndex) rangeOf(start, end int) lspRange {
+	return lspRange{Start: l.position(start), End: l.position(end)}
+}
+
+// nameRange returns the range of the identifier naming a symbol, skipping
+// the receiver of a method, or the whole symbol if the name is not found
+func (l *lineIndex) nameRange(symbol parser.Symbol) lspRange {
+	src := l.src[symbol.Start:min(symbol.End, len(l.src))]
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+	depth := 0
+	for {
+		pos, tok, lit := s.Scan()
+		switch {
+		case tok == token.EOF:
+			return l.rangeOf(symbol.Start, symbol.End)
+		case tok == token.LPAREN:
+			depth++
+		case tok == token.RPAREN:
+			depth--
+		case tok == token.IDENT && depth == 0 && lit == symbol.Name:
+			start := symbol.Start + file.Offset(pos)
+			return l.rangeOf(start, start+len(lit))
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lspSession runs the language server over the given messages and returns
+// the responses keyed by request ID
+func lspSession(t *testing.T, messages ...interface{}) map[string]json.RawMessage {
+	t.Helper()
+	var in bytes.Buffer
+	for _, msg := range messages {
+		body, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	var out bytes.Buffer
+	if err := serveLSP(&in, &out); err != nil {
+		t.Fatalf("serveLSP failed: %v", err)
+	}
+
+	responses := make(map[string]json.RawMessage)
+	conn := &lspConn{r: bufio.NewReader(&out)}
+	for {
+		body, err := conn.read()
+		if err != nil {
+			break
+		}
+		var resp struct {
+			ID     json.RawMessage `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *rpcError       `json:"error"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("Invalid response %q: %v", body, err)
+		}
+		if resp.Error != nil {
+			t.Errorf("Request %s failed: %s", resp.ID, resp.Error.Message)
+		}
+		responses[string(resp.ID)] = resp.Result
+	}
+	return responses
+}
+
+func request(id int, method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notification(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func TestLSP(t *testing.T) {
+	root := t.TempDir()
+	onDisk := `package svc
+
+// Saved exists only on disk
+func Saved() {}
+`
+	if err := os.WriteFile(filepath.Join(root, "saved.go"), []byte(onDisk), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	uri := pathToURI(filepath.Join(root, "service.go"))
+	// The open buffer differs from the (missing) file on disk
+	text := `package svc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Service processes requests
+type Service struct {
+	Name string
+}
+
+// Process handles a request
+func (s *Service) Process() {
+	fmt.Println(strings.ToUpper(s.Name))
+}
+
+func Helper() {}
+
+// More handlers follow
+
+var retries = 3
+
+const (
+	minWait = 1
+)
+`
+	doc := map[string]string{"uri": uri}
+	responses := lspSession(t,
+		request(1, "initialize", map[string]interface{}{"rootUri": pathToURI(root)}),
+		notification("initialized", map[string]interface{}{}),
+		notification("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri, "languageId": "go", "version": 1, "text": "package svc\n"},
+		}),
+		notification("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+			"contentChanges": []map[string]string{{"text": text}},
+		}),
+		request(2, "textDocument/documentSymbol", map[string]interface{}{"textDocument": doc}),
+		request(3, "workspace/symbol", map[string]string{"query": ""}),
+		request(4, "textDocument/foldingRange", map[string]interface{}{"textDocument": doc}),
+		request(5, "textDocument/selectionRange", map[string]interface{}{
+			"textDocument": doc,
+			"positions":    []lspPosition{{Line: 14, Character: 30}},
+		}),
+		request(6, "textDocument/codeAction", map[string]interface{}{
+			"textDocument": doc,
+			"range":        lspRange{Start: lspPosition{Line: 17, Character: 6}, End: lspPosition{Line: 17, Character: 6}},
+			"context":      map[string]interface{}{"diagnostics": []interface{}{}},
+		}),
+		request(8, "textDocument/codeAction", map[string]interface{}{
+			"textDocument": doc,
+			"range":        lspRange{Start: lspPosition{Line: 13, Character: 20}, End: lspPosition{Line: 13, Character: 20}},
+		}),
+		request(9, "textDocument/codeAction", map[string]interface{}{
+			"textDocument": doc,
+			"range":        lspRange{Start: lspPosition{Line: 9, Character: 2}, End: lspPosition{Line: 9, Character: 2}},
+		}),
+		request(10, "textDocument/codeAction", map[string]interface{}{
+			"textDocument": doc,
+			"range":        lspRange{Start: lspPosition{Line: 21, Character: 6}, End: lspPosition{Line: 21, Character: 6}},
+		}),
+		request(11, "textDocument/codeAction", map[string]interface{}{
+			"textDocument": doc,
+			"range":        lspRange{Start: lspPosition{Line: 24, Character: 2}, End: lspPosition{Line: 24, Character: 2}},
+		}),
+		request(7, "shutdown", nil),
+		notification("exit", nil),
+	)
+
+	if !strings.Contains(string(responses["1"]), `"documentSymbolProvider":true`) {
+		t.Errorf("initialize result = %s", responses["1"])
+	}
+
+	var symbols []lspDocumentSymbol
+	if err := json.Unmarshal(responses["2"], &symbols); err != nil {
+		t.Fatalf("documentSymbol result: %v", err)
+	}
+	kinds := make(map[string]int)
+	for _, symbol := range symbols {
+		kinds[symbol.Name] = symbol.Kind
+		if symbol.Name == "Service" && (symbol.Range.Start.Line != 8 || len(symbol.Children) != 1) {
+			t.Errorf("Service symbol = %+v", symbol)
+		}
+		// The selection covers only the name, after the receiver of a method
+		if symbol.Name == "Process" && symbol.SelectionRange != (lspRange{Start: lspPosition{Line: 13, Character: 18}, End: lspPosition{Line: 13, Character: 25}}) {
+			t.Errorf("Process selection range = %+v", symbol.SelectionRange)
+		}
+	}
+	if kinds["Service"] != lspKindStruct || kinds["Process"] != lspKindMethod || kinds["Helper"] != lspKindFunction {
+		t.Errorf("documentSymbol kinds = %v", kinds)
+	}
+
+	var infos []lspSymbolInformation
+	if err := json.Unmarshal(responses["3"], &infos); err != nil {
+		t.Fatalf("workspace/symbol result: %v", err)
+	}
+	found := make(map[string]string)
+	for _, info := range infos {
+		found[info.Name] = info.ContainerName
+	}
+	if _, ok := found["Saved"]; !ok {
+		t.Errorf("workspace/symbol missing on-disk symbol Saved: %v", found)
+	}
+	if found["Name"] != "Service" {
+		t.Errorf("workspace/symbol Name container = %q, want Service", found["Name"])
+	}
+
+	var folds []lspFoldingRange
+	if err := json.Unmarshal(responses["4"], &folds); err != nil {
+		t.Fatalf("foldingRange result: %v", err)
+	}
+	if len(folds) < 3 || folds[0].Kind != "imports" || folds[0].StartLine != 3 || folds[0].EndLine != 4 {
+		t.Errorf("foldingRange result = %+v", folds)
+	}
+
+	var selections []lspSelectionRange
+	if err := json.Unmarshal(responses["5"], &selections); err != nil {
+		t.Fatalf("selectionRange result: %v", err)
+	}
+	depth := 0
+	for r := &selections[0]; r != nil; r = r.Parent {
+		depth++
+		if r.Parent != nil && r.Parent.Range.Start.Line > r.Range.Start.Line {
+			t.Errorf("selection parent %+v does not enclose %+v", r.Parent.Range, r.Range)
+		}
+	}
+	if depth < 4 {
+		t.Errorf("selectionRange depth = %d, want nested ranges", depth)
+	}
+
+	lines := newLineIndex([]byte(text))
+	for _, tt := range []struct {
+		id    string
+		title string
+		want  string // Buffer after applying the action
+	}{
+		{"6", "Delete function Helper", strings.Replace(text, "func Helper() {}\n\n", "", 1)},
+		{"8", "Delete method Service.Process", strings.Replace(text, "// Process handles a request\nfunc (s *Service) Process() {\n\tfmt.Println(strings.ToUpper(s.Name))\n}\n\n", "", 1)},
+		{"8", "Remove doc comment of Service.Process", strings.Replace(text, "// Process handles a request\n", "", 1)},
+		{"9", "Delete field Service.Name", strings.Replace(text, "\tName string\n", "", 1)},
+		{"10", "Delete variable retries", strings.Replace(text, "var retries = 3\n\n", "", 1)},
+	} {
+		var actions []lspCodeAction
+		if err := json.Unmarshal(responses[tt.id], &actions); err != nil {
+			t.Fatalf("codeAction result: %v", err)
+		}
+		var found *lspCodeAction
+		for i := range actions {
+			if actions[i].Title == tt.title {
+				found = &actions[i]
+			}
+		}
+		if found == nil {
+			t.Errorf("codeAction %s = %+v, want %q", tt.id, actions, tt.title)
+			continue
+		}
+		edits := found.Edit.Changes[uri]
+		if len(edits) != 1 {
+			t.Fatalf("%s edits = %+v, want one", tt.title, edits)
+		}
+		from, to := lines.offset(edits[0].Range.Start), lines.offset(edits[0].Range.End)
+		if got := text[:from] + edits[0].NewText + text[to:]; got != tt.want {
+			t.Errorf("%s gives:\n%s\nwant:\n%s", tt.title, got, tt.want)
+		}
+	}
+
+	// Specs of a const group are not deleted on their own
+	var grouped []lspCodeAction
+	if err := json.Unmarshal(responses["11"], &grouped); err != nil {
+		t.Fatalf("codeAction result: %v", err)
+	}
+	for _, action := range grouped {
+		if strings.HasPrefix(action.Title, "Delete") {
+			t.Errorf("codeAction 11 offers %q", action.Title)
+		}
+	}
+}
+
+func TestLineIndex(t *testing.T) {
+	src := []byte("a\n😀b\nc")
+	lines := newLineIndex(src)
+	// The emoji is four bytes and two UTF-16 code units
+	pos := lines.position(6)
+	if pos != (lspPosition{Line: 1, Character: 2}) {
+		t.Errorf("position(6) = %+v, want 1:2", pos)
+	}
+	if off := lines.offset(lspPosition{Line: 1, Character: 2}); off != 6 {
+		t.Errorf("offset(1:2) = %d, want 6", off)
+	}
+	if off := lines.offset(lspPosition{Line: 2, Character: 10}); off != len(src) {
+		t.Errorf("offset(2:10) = %d, want %d", off, len(src))
+	}
+}
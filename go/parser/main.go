@@ -2,22 +2,78 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/rosen/go-parser/parser"
 )
 
 type Command struct {
-	Operation string              `json:"operation"` // "parse" or "edit"
-	File      string              `json:"file"`
-	Edit      *parser.EditRequest `json:"edit,omitempty"`
+	Operation  string                    `json:"operation"` // "parse", "parse-package", "get", "skeleton", "context", "edit", "rename", "create", "move" or "references"
+	File       string                    `json:"file"`
+	Dir        string                    `json:"dir,omitempty"`
+	Get        *parser.GetRequest        `json:"get,omitempty"`
+	Skeleton   *parser.SkeletonRequest   `json:"skeleton,omitempty"`
+	Context    *parser.ContextRequest    `json:"context,omitempty"`
+	Edit       *parser.EditRequest       `json:"edit,omitempty"`
+	Rename     *parser.RenameRequest     `json:"rename,omitempty"`
+	Create     *parser.CreateRequest     `json:"create,omitempty"`
+	Move       *parser.MoveRequest       `json:"move,omitempty"`
+	References *parser.ReferencesRequest `json:"references,omitempty"`
+	Options    *parser.ParseOptions      `json:"options,omitempty"`
+	Package    *parser.PackageOptions    `json:"package,omitempty"`
 }
 
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error"`
+	Success bool                 `json:"success"`
+	Error   string               `json:"error"`
+	Code    parser.ErrorCode     `json:"code,omitempty"`
+	Details *parser.ErrorDetails `json:"details,omitempty"`
+}
+
+// exitCodes maps error codes onto distinct process exit codes so that
+// callers can branch on the failure without reading stderr
+var exitCodes = map[parser.ErrorCode]int{
+	parser.CodeInternal:           1,
+	parser.CodeInvalidRequest:     2,
+	parser.CodeFileNotFound:       3,
+	parser.CodeReadFailed:         4,
+	parser.CodeParseFailed:        5,
+	parser.CodeSymbolNotFound:     6,
+	parser.CodeAmbiguousSymbol:    7,
+	parser.CodeContentSyntax:      8,
+	parser.CodeGeneratedFile:      9,
+	parser.CodeFormatFailed:       10,
+	parser.CodeFileChanged:        11,
+	parser.CodeWriteFailed:        12,
+	parser.CodeNameConflict:       13,
+	parser.CodeStatementNotFound:  14,
+	parser.CodeAmbiguousStatement: 15,
+	parser.CodeFileExists:         16,
+}
+
+// codedError returns err as a structured error, assigning code to errors
+// that do not carry one
+func codedError(code parser.ErrorCode, err error) *parser.Error {
+	var perr *parser.Error
+	if errors.As(err, &perr) {
+		return perr
+	}
+	return &parser.Error{Code: code, Message: err.Error()}
+}
+
+// exitCode returns the process exit code for err
+func exitCode(err error) int {
+	if code, ok := exitCodes[codedError(parser.CodeInternal, err).Code]; ok {
+		return code
+	}
+	return 1
 }
 
 func validateEditRequest(req *parser.EditRequest) error {
@@ -29,20 +85,31 @@ func validateEditRequest(req *parser.EditRequest) error {
 	}
 	// Check if file exists
 	if _, err := os.Stat(req.Path); os.IsNotExist(err) {
-		return fmt.Errorf("file does not exist: %s", req.Path)
+		return &parser.Error{Code: parser.CodeFileNotFound, Message: fmt.Sprintf("file does not exist: %s", req.Path)}
 	}
-	if req.Symbol == "" {
+	// Inserts at a file position have no symbol to anchor on
+	if req.Symbol == "" && !(req.EditType == "insert" && req.Insert != nil && parser.IsFilePosition(req.Insert.Position)) {
 		return fmt.Errorf("symbol name is required")
 	}
 	if req.EditType == "" {
 		return fmt.Errorf("edit type is required")
 	}
-	if req.EditType != "replace" && req.EditType != "insert" && req.EditType != "delete" {
-		return fmt.Errorf("invalid edit type '%s': must be 'replace', 'insert', or 'delete'", req.EditType)
+	switch req.EditType {
+	case "replace", "insert", "delete", "replace-body", "set-doc", "insert-statement", "replace-statement", "delete-statement":
+	default:
+		return fmt.Errorf("invalid edit type '%s': must be 'replace', 'insert', 'delete', 'replace-body', 'set-doc', 'insert-statement', 'replace-statement' or 'delete-statement'", req.EditType)
 	}
-	if req.EditType != "delete" && req.Content == "" {
+	if req.EditType != "delete" && req.EditType != "set-doc" && req.EditType != "delete-statement" && req.Content == "" {
 		return fmt.Errorf("content is required for %s operations", req.EditType)
 	}
+	if strings.HasSuffix(req.EditType, "-statement") {
+		if req.Statement == nil || (req.Statement.Anchor == "") == (req.Statement.Path == "") {
+			return fmt.Errorf("statement anchor or path is required for %s operations", req.EditType)
+		}
+		if req.EditType == "insert-statement" && req.Statement.Position != "before" && req.Statement.Position != "after" {
+			return fmt.Errorf("invalid position '%s': must be 'before' or 'after'", req.Statement.Position)
+		}
+	}
 	if req.EditType == "insert" {
 		if req.Insert == nil {
 			return fmt.Errorf("insert configuration is required for insert operations")
@@ -50,114 +117,250 @@ func validateEditRequest(req *parser.EditRequest) error {
 		if req.Insert.Position == "" {
 			return fmt.Errorf("position is required for insert operations")
 		}
-		if req.Insert.Position != "before" && req.Insert.Position != "after" {
-			return fmt.Errorf("invalid position '%s': must be 'before' or 'after'", req.Insert.Position)
-		}
-		if req.Insert.RelativeToSymbol == "" {
-			return fmt.Errorf("target symbol (relative-to) is required for insert operations")
+		if !parser.IsFilePosition(req.Insert.Position) {
+			if req.Insert.Position != "before" && req.Insert.Position != "after" {
+				return fmt.Errorf("invalid position '%s': must be 'before', 'after', 'start', 'after-imports', 'end', 'first-method-of:T' or 'last-method-of:T'", req.Insert.Position)
+			}
+			if req.Insert.RelativeToSymbol == "" {
+				return fmt.Errorf("target symbol (relative-to) is required for insert operations")
+			}
 		}
 	}
 	return nil
 }
 
+// stdout carries the JSON results and protocol messages, and nothing else
+var stdout io.Writer = os.Stdout
+
+// newLogger creates the structured logger for diagnostics on w
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: must be debug, info, warn or error", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q: must be text or json", format)
+}
+
 func main() {
+	// Anything printed to os.Stdout outside the protocol, by this program or
+	// its dependencies, is diverted to stderr so it cannot corrupt the output
+	stdout = os.Stdout
+	os.Stdout = os.Stderr
+
+	if len(os.Args) > 1 {
+		if _, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(runCLI(os.Args[1], os.Args[2:]))
+		}
+	}
+
 	// Check if we're reading from stdin
 	inputFlag := flag.String("input", "", "Input source ('-' for stdin)")
+	serveFlag := flag.Bool("serve", false, "Serve newline-delimited JSON-RPC 2.0 requests on stdin/stdout")
+	lspFlag := flag.Bool("lsp", false, "Run as a Language Server Protocol server on stdin/stdout")
+	mcpFlag := flag.Bool("mcp", false, "Run as a Model Context Protocol tool server on stdin/stdout")
+	logLevel := flag.String("log-level", "warn", "Minimum level of diagnostics written to stderr: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Format of diagnostics written to stderr: text or json")
 	flag.Parse()
 
+	logger, err := newLogger(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		err := codedError(parser.CodeInvalidRequest, err)
+		writeError(err)
+		os.Exit(exitCode(err))
+	}
+	slog.SetDefault(logger)
+	parser.SetLogger(logger)
+
+	if *mcpFlag {
+		if err := serveMCP(os.Stdin, stdout); err != nil {
+			writeError(fmt.Errorf("MCP server failed: %v", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *lspFlag {
+		if err := serveLSP(os.Stdin, stdout); err != nil {
+			writeError(fmt.Errorf("language server failed: %v", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *serveFlag {
+		if err := serve(os.Stdin, stdout); err != nil {
+			writeError(fmt.Errorf("server failed: %v", err))
+			os.Exit(1)
+		}
+		return
+	}
+
 	var cmd Command
 	if *inputFlag == "-" {
 		// Read command from stdin
 		decoder := json.NewDecoder(os.Stdin)
 		if err := decoder.Decode(&cmd); err != nil {
-			writeError(fmt.Sprintf("failed to parse input JSON: %v", err))
-			os.Exit(1)
-		}
-		// Validate the JSON input
-		if cmd.Operation == "edit" {
-			if err := validateEditRequest(cmd.Edit); err != nil {
-				writeError(err.Error())
-				os.Exit(1)
-			}
+			writeError(codedError(parser.CodeInvalidRequest, fmt.Errorf("failed to parse input JSON: %v", err)))
+			os.Exit(exitCodes[parser.CodeInvalidRequest])
 		}
 	} else {
-		// Use command line flags
-		filePath := flag.String("file", "", "Path to the Go file")
-		symbol := flag.String("symbol", "", "Symbol to edit")
-		editType := flag.String("type", "", "Edit type (replace/insert/delete)")
-		content := flag.String("content", "", "New content")
-		position := flag.String("position", "", "Position (before/after) for insert operations")
-		relativeToSymbol := flag.String("relative-to", "", "Target symbol for insert operations")
-
-		flag.Parse()
-
-		if *filePath == "" {
-			writeError("file path is required")
-			os.Exit(1)
-		}
+		printUsage()
+		os.Exit(exitCodes[parser.CodeInvalidRequest])
+	}
 
-		cmd = Command{
-			File: *filePath,
-		}
+	result, err := execute(cmd)
+	if err != nil {
+		writeError(err)
+		os.Exit(exitCode(err))
+	}
+	writeJSON(result)
+}
 
-		if *symbol != "" {
-			cmd.Operation = "edit"
-			editReq := &parser.EditRequest{
-				Path:     *filePath,
-				Symbol:   *symbol,
-				EditType: *editType,
-				Content:  *content,
-			}
+// execute runs a single command and returns its JSON-serialisable result
+func execute(cmd Command) (interface{}, error) {
+	slog.Debug("executing command", "operation", cmd.Operation, "file", cmd.File)
+	switch cmd.Operation {
+	case "parse":
+		var opts parser.ParseOptions
+		if cmd.Options != nil {
+			opts = *cmd.Options
+		}
+		result, err := parser.ParseWithOptions(cmd.File, opts)
+		if err != nil {
+			return nil, fileError(fmt.Errorf("failed to parse file: %w", err))
+		}
+		return result, nil
 
-			// Add insert configuration if needed
-			if *editType == "insert" {
-				editReq.Insert = &parser.InsertConfig{
-					Position:         *position,
-					RelativeToSymbol: *relativeToSymbol,
-				}
-			}
+	case "parse-package":
+		dir := cmd.Dir
+		if dir == "" {
+			dir = cmd.File
+		}
+		if dir == "" {
+			return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("directory is required for parse-package operation"))
+		}
+		var opts parser.PackageOptions
+		if cmd.Package != nil {
+			opts = *cmd.Package
+		}
+		if cmd.Options != nil {
+			opts.ParseOptions = *cmd.Options
+		}
+		result, err := parser.ParsePackage(dir, opts)
+		if err != nil {
+			return nil, fileError(fmt.Errorf("failed to parse package: %w", err))
+		}
+		return result, nil
 
-			// Validate the edit request
-			if err := validateEditRequest(editReq); err != nil {
-				writeError(err.Error())
-				os.Exit(1)
-			}
+	case "get":
+		if cmd.Get == nil {
+			return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("get request is required for get operation"))
+		}
+		result := parser.Get(*cmd.Get)
+		if !result.Success {
+			return nil, &parser.Error{Code: result.Code, Message: result.Error, Details: result.Details}
+		}
+		return result, nil
 
-			cmd.Edit = editReq
-		} else {
-			cmd.Operation = "parse"
+	case "skeleton":
+		if cmd.Skeleton == nil {
+			return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("skeleton request is required for skeleton operation"))
 		}
-	}
+		result := parser.Skeleton(*cmd.Skeleton)
+		if !result.Success {
+			return nil, &parser.Error{Code: result.Code, Message: result.Error, Details: result.Details}
+		}
+		return result, nil
 
-	switch cmd.Operation {
-	case "parse":
-		result, err := parser.Parse(cmd.File)
-		if err != nil {
-			writeError(fmt.Sprintf("failed to parse file: %v", err))
-			os.Exit(1)
+	case "context":
+		if cmd.Context == nil {
+			return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("context request is required for context operation"))
+		}
+		result := parser.Context(*cmd.Context)
+		if !result.Success {
+			return nil, &parser.Error{Code: result.Code, Message: result.Error, Details: result.Details}
 		}
-		writeJSON(result)
+		return result, nil
 
 	case "edit":
 		if cmd.Edit == nil {
-			writeError("edit request is required for edit operation")
-			os.Exit(1)
+			return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("edit request is required for edit operation"))
+		}
+		if err := validateEditRequest(cmd.Edit); err != nil {
+			return nil, codedError(parser.CodeInvalidRequest, err)
 		}
 		result := parser.Edit(*cmd.Edit)
 		if !result.Success {
-			writeError(result.Error)
-			os.Exit(1)
+			return nil, parser.ErrorFromResult(result)
+		}
+		return result, nil
+
+	case "rename":
+		if cmd.Rename == nil {
+			return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("rename request is required for rename operation"))
+		}
+		result := parser.Rename(*cmd.Rename)
+		if !result.Success {
+			return nil, &parser.Error{Code: result.Code, Message: result.Error, Details: result.Details}
+		}
+		return result, nil
+
+	case "create":
+		if cmd.Create == nil {
+			return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("create request is required for create operation"))
+		}
+		result := parser.Create(*cmd.Create)
+		if !result.Success {
+			return nil, parser.ErrorFromResult(result)
+		}
+		return result, nil
+
+	case "move":
+		if cmd.Move == nil {
+			return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("move request is required for move operation"))
+		}
+		result := parser.Move(*cmd.Move)
+		if !result.Success {
+			return nil, &parser.Error{Code: result.Code, Message: result.Error, Details: result.Details}
+		}
+		return result, nil
+
+	case "references":
+		if cmd.References == nil {
+			return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("references request is required for references operation"))
+		}
+		result := parser.References(*cmd.References)
+		if !result.Success {
+			return nil, &parser.Error{Code: result.Code, Message: result.Error, Details: result.Details}
 		}
-		writeJSON(result)
+		return result, nil
 
 	default:
-		writeError(fmt.Sprintf("unknown operation: %s", cmd.Operation))
-		os.Exit(1)
+		return nil, codedError(parser.CodeInvalidRequest, fmt.Errorf("unknown operation: %s", cmd.Operation))
+	}
+}
+
+// fileError classifies a failure to read or parse an input file
+func fileError(err error) *parser.Error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return codedError(parser.CodeFileNotFound, err)
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return codedError(parser.CodeReadFailed, err)
 	}
+	return codedError(parser.CodeParseFailed, err)
 }
 
 func writeJSON(v interface{}) {
-	if err := json.NewEncoder(os.Stdout).Encode(v); err != nil {
+	if err := json.NewEncoder(stdout).Encode(v); err != nil {
 		errResp := ErrorResponse{
 			Success: false,
 			Error:   fmt.Sprintf("failed to write JSON response: %v", err),
@@ -167,10 +370,13 @@ func writeJSON(v interface{}) {
 	}
 }
 
-func writeError(msg string) {
+func writeError(err error) {
+	perr := codedError(parser.CodeInternal, err)
 	errResp := ErrorResponse{
 		Success: false,
-		Error:   msg,
+		Error:   perr.Message,
+		Code:    perr.Code,
+		Details: perr.Details,
 	}
 	json.NewEncoder(os.Stderr).Encode(errResp)
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain lets tests run the real binary entry point in a subprocess
+func TestMain(m *testing.M) {
+	if os.Getenv("GOPARSER_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain executes main in a subprocess and returns its stdout, stderr and exit code
+func runMain(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "GOPARSER_RUN_MAIN=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	code := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("Failed to run main: %v", err)
+	}
+	return stdout.String(), stderr.String(), code
+}
+
+// jsonLines decodes every line of out as a JSON object, failing on anything else
+func jsonLines(t *testing.T, stream, out string) []map[string]interface{} {
+	t.Helper()
+	var docs []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			t.Errorf("%s line is not JSON: %q", stream, scanner.Text())
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// TestStdoutCarriesOnlyProtocol checks that diagnostics never reach stdout,
+// whatever the operation, and that they are written to stderr instead
+func TestStdoutCarriesOnlyProtocol(t *testing.T) {
+	initial := `package test
+
+// Process handles data
+func Process() error {
+	return nil
+}
+
+func Cleanup() {}
+`
+	commands := []struct {
+		name     string
+		command  map[string]interface{}
+		wantCode int
+	}{
+		{
+			name:    "parse",
+			command: map[string]interface{}{"operation": "parse"},
+		},
+		{
+			name: "insert",
+			command: map[string]interface{}{"operation": "edit", "edit": map[string]interface{}{
+				"EditType": "insert",
+				"Symbol":   "Validate",
+				"Content":  "func Validate() error { return nil }",
+				"Insert":   map[string]string{"Position": "before", "RelativeToSymbol": "Process"},
+			}},
+		},
+		{
+			name: "delete",
+			command: map[string]interface{}{"operation": "edit", "edit": map[string]interface{}{
+				"EditType": "delete",
+				"Symbol":   "Cleanup",
+			}},
+		},
+		{
+			name: "missing symbol",
+			command: map[string]interface{}{"operation": "edit", "edit": map[string]interface{}{
+				"EditType": "delete",
+				"Symbol":   "Missing",
+			}},
+			wantCode: exitCodes["SYMBOL_NOT_FOUND"],
+		},
+	}
+
+	for _, tt := range commands {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.go")
+			if err := os.WriteFile(path, []byte(initial), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tt.command["file"] = path
+			if edit, ok := tt.command["edit"].(map[string]interface{}); ok {
+				edit["Path"] = path
+			}
+			input, err := json.Marshal(tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stdout, stderr, code := runMain(t, string(input), "-input", "-", "-log-level", "debug", "-log-format", "json")
+			if code != tt.wantCode {
+				t.Errorf("Exit code = %d, want %d (stderr: %s)", code, tt.wantCode, stderr)
+			}
+
+			docs := jsonLines(t, "stdout", stdout)
+			if tt.wantCode == 0 && len(docs) != 1 {
+				t.Errorf("stdout has %d JSON documents, want exactly 1:\n%s", len(docs), stdout)
+			}
+			if tt.wantCode != 0 && stdout != "" {
+				t.Errorf("stdout should be empty on failure, got:\n%s", stdout)
+			}
+
+			// stderr holds JSON log records, plus the error response on failure
+			logs := 0
+			for _, doc := range jsonLines(t, "stderr", stderr) {
+				if _, ok := doc["level"]; ok {
+					logs++
+				}
+			}
+			if logs == 0 {
+				t.Errorf("No debug log records on stderr:\n%s", stderr)
+			}
+		})
+	}
+}
+
+func TestServeStdoutCarriesOnlyProtocol(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.go")
+	if err := os.WriteFile(path, []byte("package test\n\nfunc A() {}\n\nfunc B() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"edit","params":{"edit":{"Path":` + quote(path) + `,"EditType":"delete","Symbol":"B"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"parse","params":{"file":` + quote(path) + `}}`,
+	}
+	stdout, stderr, code := runMain(t, strings.Join(requests, "\n")+"\n", "-serve", "-log-level", "debug")
+	if code != 0 {
+		t.Fatalf("Exit code = %d (stderr: %s)", code, stderr)
+	}
+	if docs := jsonLines(t, "stdout", stdout); len(docs) != 2 {
+		t.Errorf("stdout has %d responses, want 2:\n%s", len(docs), stdout)
+	}
+	if !strings.Contains(stderr, "level=DEBUG") {
+		t.Errorf("No text log records on stderr:\n%s", stderr)
+	}
+}
+
+func TestInvalidLogFlags(t *testing.T) {
+	for _, args := range [][]string{{"-log-level", "loud"}, {"-log-format", "xml"}} {
+		stdout, _, code := runMain(t, "", append(args, "-input", "-")...)
+		if code != exitCodes["INVALID_REQUEST"] || stdout != "" {
+			t.Errorf("%v: exit code = %d, stdout = %q", args, code, stdout)
+		}
+	}
+}
@@ -0,0 +1,606 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/rosen/go-parser/parser"
+)
+
+// mcpProtocolVersions lists the Model Context Protocol revisions the server
+// speaks, newest first
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// mcpTool describes a tool in tools/list
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// mcpContent is a text content block of a tool result
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// mcpToolResult is the result of tools/call
+type mcpToolResult struct {
+	Content           []mcpContent `json:"content"`
+	StructuredContent interface{}  `json:"structuredContent,omitempty"`
+	IsError           bool         `json:"isError,omitempty"`
+}
+
+// mcpEditResult is the structured result of the edit tools
+type mcpEditResult struct {
+	Success bool   `json:"success"`
+	Path    string `json:"path"`
+	Diff    string `json:"diff"`
+	Content string `json:"content"`
+
+	ResolvedSymbol        string   `json:"resolvedSymbol,omitempty"`        // Symbol edited in place of a misspelled one
+	BrokenImplementations []string `json:"brokenImplementations,omitempty"` // Types that stopped satisfying an edited interface
+	UnresolvedImports     []string `json:"unresolvedImports,omitempty"`     // Packages used by the code that no import was found for
+}
+
+// schemaDescriptions documents the request fields exposed in tool schemas,
+// by field name or by Type.Field
+var schemaDescriptions = map[string]string{
+	"Path":                     "Absolute path of the Go file",
+	"Symbol":                   "Name of the declaration to target, Type.Method for a method or interface method, Type.Field for a struct field, or the name of the new declaration for inserts",
+	"Content":                  "Go source of the declaration, including its doc comment, or a field line such as Name string `json:\"name\"` when targeting a struct field",
+	"Insert":                   "Where to place the new declaration",
+	"AllowGenerated":           "Permit edits to files marked \"Code generated ... DO NOT EDIT.\"",
+	"CheckImplementations":     "When editing an interface method, report the package types that stop satisfying the interface",
+	"FuzzyMatch":               "Edit the only close match when the target symbol is misspelled or miscased; the result names the symbol used",
+	"Position":                 "Where to insert: before or after RelativeToSymbol, start (after the package clause), after-imports, end, first-method-of:T or last-method-of:T to group a new method with the methods of type T",
+	"RelativeToSymbol":         "Name of the existing declaration, or Type.Field, to insert relative to; required for before and after",
+	"Budget":                   "Maximum total size of the returned sources in bytes, or 0 for no limit",
+	"Keep":                     "Functions, methods or types whose bodies are kept in full; a type keeps all its methods",
+	"Doc":                      "How the doc comment is laid out",
+	"Width":                    "Maximum length of a comment line including \"// \", 0 for 80 or negative to disable wrapping",
+	"Godoc":                    "Require the comment to start with the symbol name and give Deprecated: notes their own paragraph",
+	"Statement":                "Statement of the Symbol function to edit, selected by exactly one of Anchor or Path",
+	"StatementConfig.Path":     "Path of the statement: 1-based indices alternating between a statement and one of its nested blocks, such as 2.1.3 for the third statement in the first block of the second statement",
+	"StatementConfig.Position": "Whether to insert before or after the selected statement",
+	"Anchor":                   "Source snippet contained in the statement; whitespace is normalized and the innermost matching statement is used",
+	"CreateRequest.Path":       "Absolute path of the Go file to create",
+	"CreateRequest.Content":    "Declarations of the new file without a package clause; imports may be omitted",
+	"MoveRequest.Path":         "Absolute path of the Go file declaring the symbol",
+	"MoveRequest.Symbol":       "Name of the declaration to move, Type.Method for a method, or a variable or type declared in a group",
+	"Destination":              "Absolute path of the Go file to move the declaration to, in the same directory; created when missing",
+	"WithMethods":              "When moving a type, move the methods the source file declares on it as well",
+	"AllowConstraintMismatch":  "Permit moving between files whose build constraints or GOOS/GOARCH file name suffixes differ",
+	"RenameRequest.Symbol":     "Name of the declaration to rename, or Type.Member for a method, field or interface method",
+	"NewName":                  "New identifier for the symbol",
+	"ReferencesRequest.Symbol": "Name of the declaration, or Type.Member for a method, field or interface method",
+	"Root":                     "Directory whose packages are searched, defaulting to the root of the module containing Path",
+	"Package":                  "Package name, inferred from the other files in the directory when empty",
+	"ExternalTest":             "For a _test.go file, use the external test package such as foo_test",
+	"Overwrite":                "Replace the file if it already exists",
+	"Deprecated":               "Append a \"Deprecated: \" paragraph with this text",
+	"depth":                    "Levels of local types, named closures and subtests to report inside function bodies",
+}
+
+// schemaEnums restricts request fields to their accepted values, by field
+// name or by Type.Field
+var schemaEnums = map[string][]string{
+	"StatementConfig.Position": {"before", "after"},
+}
+
+// jsonSchema derives a JSON Schema from a Go type using the names its
+// fields have in JSON, leaving out the omitted fields
+func jsonSchema(t reflect.Type, omit ...string) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			// Embedded structs contribute their fields directly
+			if field.Anonymous && name == "" {
+				for k, v := range jsonSchema(field.Type, omit...)["properties"].(map[string]interface{}) {
+					properties[k] = v
+				}
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if contains(omit, name) {
+				continue
+			}
+			schema := jsonSchema(field.Type)
+			// Type.Field entries override the description shared by a name
+			if desc, ok := schemaDescriptions[t.Name()+"."+name]; ok {
+				schema["description"] = desc
+			} else if desc, ok := schemaDescriptions[name]; ok {
+				schema["description"] = desc
+			}
+			if enum, ok := schemaEnums[t.Name()+"."+name]; ok {
+				schema["enum"] = enum
+			} else if enum, ok := schemaEnums[name]; ok {
+				schema["enum"] = enum
+			}
+			properties[name] = schema
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+	return map[string]interface{}{}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// withRequired marks properties of an object schema as required
+func withRequired(schema map[string]interface{}, required ...string) map[string]interface{} {
+	schema["required"] = required
+	return schema
+}
+
+// mcpTools returns the tools offered by the server
+func mcpTools() []mcpTool {
+	editRequest := reflect.TypeOf(parser.EditRequest{})
+	insertSchema := withRequired(jsonSchema(reflect.TypeOf(parser.InsertConfig{})), "Position")
+
+	parseSchema := jsonSchema(reflect.TypeOf(parser.ParseOptions{}))
+	parseSchema["properties"].(map[string]interface{})["file"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Absolute path of the Go file to parse",
+	}
+
+	body := jsonSchema(editRequest, "EditType", "Insert", "Doc", "Statement", "CheckImplementations")
+	body["properties"].(map[string]interface{})["Content"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Statements of the new body, without the surrounding braces",
+	}
+
+	doc := jsonSchema(editRequest, "EditType", "Insert", "Doc", "Statement", "CheckImplementations")
+	doc["properties"].(map[string]interface{})["Content"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Text of the doc comment, with or without // markers; empty removes the comment",
+	}
+
+	statement := func(required ...string) map[string]interface{} {
+		schema := jsonSchema(editRequest, "EditType", "Insert", "Doc", "CheckImplementations")
+		schema["properties"].(map[string]interface{})["Content"] = map[string]interface{}{
+			"type":        "string",
+			"description": "One or more statements",
+		}
+		return withRequired(schema, append([]string{"Path", "Symbol", "Statement"}, required...)...)
+	}
+
+	insert := jsonSchema(editRequest, "EditType", "Statement")
+	insert["properties"].(map[string]interface{})["Insert"] = insertSchema
+
+	return []mcpTool{
+		{
+			Name:        "parse",
+			Description: "List the declarations of a Go file with their kinds, offsets and doc comments, plus file metadata such as imports and build constraints.",
+			InputSchema: withRequired(parseSchema, "file"),
+		},
+		{
+			Name:        "get_symbol",
+			Description: "Return the source text, including the doc comment, and the range of one declaration in a Go file. Use Type.Member to select a method, struct field or interface method.",
+			InputSchema: withRequired(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"file":          map[string]interface{}{"type": "string", "description": "Absolute path of the Go file"},
+					"symbol":        map[string]interface{}{"type": "string", "description": "Name of the declaration"},
+					"signatureOnly": map[string]interface{}{"type": "boolean", "description": "Elide the function body and return just the signature"},
+				},
+			}, "file", "symbol"),
+		},
+		{
+			Name:        "skeleton",
+			Description: "Return a Go file with every function and method body replaced by { ... }, keeping the named symbols in full, plus a map from each output line to its line in the original file.",
+			InputSchema: withRequired(jsonSchema(reflect.TypeOf(parser.SkeletonRequest{})), "Path"),
+		},
+		{
+			Name:        "context",
+			Description: "Return a function or method in full together with the signatures of the types, constants, variables and functions of its package that it references, ranked by relevance and trimmed to a size budget.",
+			InputSchema: withRequired(jsonSchema(reflect.TypeOf(parser.ContextRequest{})), "Path", "Symbol"),
+		},
+		{
+			Name:        "edit_replace",
+			Description: "Replace a declaration in a Go file with new source and return a diff of the change.",
+			InputSchema: withRequired(jsonSchema(editRequest, "EditType", "Insert", "Doc", "Statement"), "Path", "Symbol", "Content"),
+		},
+		{
+			Name:        "edit_insert",
+			Description: "Insert a new declaration before or after an existing one, at the start or end of a Go file, after its imports, or next to the methods of a type, and return a diff of the change.",
+			InputSchema: withRequired(insert, "Path", "Content", "Insert"),
+		},
+		{
+			Name:        "edit_replace_body",
+			Description: "Replace only the statements of a function or method in a Go file, keeping its doc comment, receiver and signature, and return a diff of the change.",
+			InputSchema: withRequired(body, "Path", "Symbol", "Content"),
+		},
+		{
+			Name:        "edit_set_doc",
+			Description: "Replace, add or remove the doc comment of a declaration, grouped constant or variable, struct field or interface method in a Go file, wrapping the text, and return a diff of the change.",
+			InputSchema: withRequired(doc, "Path", "Symbol"),
+		},
+		{
+			Name:        "edit_delete",
+			Description: "Delete a declaration and its doc comment from a Go file and return a diff of the change.",
+			InputSchema: withRequired(jsonSchema(editRequest, "EditType", "Insert", "Content", "Doc", "Statement"), "Path", "Symbol"),
+		},
+		{
+			Name:        "create_file",
+			Description: "Create a Go file holding the given declarations, with the package name inferred from the other files in its directory and the imports the code needs added, and return a diff of the change. Refuses to replace an existing file unless Overwrite is set.",
+			InputSchema: withRequired(jsonSchema(reflect.TypeOf(parser.CreateRequest{})), "Path"),
+		},
+		{
+			Name:        "move",
+			Description: "Move a declaration with its doc comment, and optionally the methods of a type, to another Go file of the same package, creating that file if needed and fixing the imports of both. Either both files are written or neither is. Returns a diff per file.",
+			InputSchema: withRequired(jsonSchema(reflect.TypeOf(parser.MoveRequest{})), "Path", "Symbol", "Destination"),
+		},
+		{
+			Name:        "find_references",
+			Description: "Find every reference to a declaration, method or field across the packages of the module, including tests, using type information. Each reference has its file, line, column and byte range, the top-level declaration containing it, and whether it reads, writes or calls the symbol.",
+			InputSchema: withRequired(jsonSchema(reflect.TypeOf(parser.ReferencesRequest{})), "Path", "Symbol"),
+		},
+		{
+			Name:        "rename",
+			Description: "Rename a declaration, method or field and every reference to it in its package, including _test.go files, using type information. Renaming a method also renames the interface methods it satisfies and their other implementations. Refuses renames that would collide with or be shadowed by another name. Returns a diff per changed file.",
+			InputSchema: withRequired(jsonSchema(reflect.TypeOf(parser.RenameRequest{})), "Path", "Symbol", "NewName"),
+		},
+		{
+			Name:        "edit_insert_statement",
+			Description: "Insert statements before or after the statement of a function body matching an anchor snippet or statement path, and return a diff of the change. Use this to add a guard clause or log line without replacing the function.",
+			InputSchema: statement("Content"),
+		},
+		{
+			Name:        "edit_replace_statement",
+			Description: "Replace the statement of a function body matching an anchor snippet or statement path, and return a diff of the change.",
+			InputSchema: statement("Content"),
+		},
+		{
+			Name:        "edit_delete_statement",
+			Description: "Delete the statement of a function body matching an anchor snippet or statement path, and return a diff of the change.",
+			InputSchema: statement(),
+		},
+	}
+}
+
+// serveMCP runs a Model Context Protocol tool server over newline-delimited
+// JSON-RPC 2.0 on r and w until r is exhausted
+func serveMCP(r io.Reader, w io.Writer) error {
+	out := newRPCWriter(w)
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var req rpcRequest
+			if jsonErr := json.Unmarshal(line, &req); jsonErr != nil {
+				out.write(rpcResponse{
+					JSONRPC: "2.0",
+					ID:      rpcNullID,
+					Error:   &rpcError{Code: rpcParseError, Message: fmt.Sprintf("invalid JSON: %v", jsonErr)},
+				})
+			} else if req.Method != "" {
+				result, rpcErr := handleMCP(req)
+				if len(req.ID) > 0 {
+					out.write(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handleMCP dispatches a single MCP request or notification
+func handleMCP(req rpcRequest) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return nil, invalidParams(err)
+			}
+		}
+		version := mcpProtocolVersions[0]
+		if contains(mcpProtocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "goparser", "version": "1.0.0"},
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		return map[string]interface{}{"tools": mcpTools()}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if len(params.Arguments) == 0 {
+			params.Arguments = json.RawMessage("{}")
+		}
+		result, err := callMCPTool(params.Name, params.Arguments)
+		if errors.Is(err, errUnknownTool) {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		if err != nil {
+			// Tool failures are reported to the model rather than as protocol errors
+			return mcpToolResult{
+				Content:           []mcpContent{{Type: "text", Text: err.Error()}},
+				StructuredContent: codedError(parser.CodeInternal, err),
+				IsError:           true,
+			}, nil
+		}
+		return result, nil
+	}
+
+	if strings.HasPrefix(req.Method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unsupported method: %s", req.Method)}
+}
+
+var errUnknownTool = errors.New("unknown tool")
+
+// callMCPTool runs a tool with its JSON arguments
+func callMCPTool(name string, args json.RawMessage) (mcpToolResult, error) {
+	switch name {
+	case "parse":
+		var input struct {
+			File string `json:"file"`
+			parser.ParseOptions
+		}
+		if err := json.Unmarshal(args, &input); err != nil {
+			return mcpToolResult{}, fmt.Errorf("invalid arguments: %v", err)
+		}
+		result, err := execute(Command{Operation: "parse", File: input.File, Options: &input.ParseOptions})
+		if err != nil {
+			return mcpToolResult{}, err
+		}
+		return structuredResult(result)
+
+	case "get_symbol":
+		var input struct {
+			File          string `json:"file"`
+			Symbol        string `json:"symbol"`
+			SignatureOnly bool   `json:"signatureOnly"`
+		}
+		if err := json.Unmarshal(args, &input); err != nil {
+			return mcpToolResult{}, fmt.Errorf("invalid arguments: %v", err)
+		}
+		result, err := execute(Command{Operation: "get", File: input.File, Get: &parser.GetRequest{Path: input.File, Symbol: input.Symbol, SignatureOnly: input.SignatureOnly}})
+		if err != nil {
+			return mcpToolResult{}, err
+		}
+		return mcpToolResult{
+			Content:           []mcpContent{{Type: "text", Text: result.(parser.GetResult).Source}},
+			StructuredContent: result,
+		}, nil
+
+	case "skeleton":
+		var req parser.SkeletonRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return mcpToolResult{}, fmt.Errorf("invalid arguments: %v", err)
+		}
+		result, err := execute(Command{Operation: "skeleton", File: req.Path, Skeleton: &req})
+		if err != nil {
+			return mcpToolResult{}, err
+		}
+		return mcpToolResult{
+			Content:           []mcpContent{{Type: "text", Text: result.(parser.SkeletonResult).Content}},
+			StructuredContent: result,
+		}, nil
+
+	case "context":
+		var req parser.ContextRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return mcpToolResult{}, fmt.Errorf("invalid arguments: %v", err)
+		}
+		result, err := execute(Command{Operation: "context", File: req.Path, Context: &req})
+		if err != nil {
+			return mcpToolResult{}, err
+		}
+		return structuredResult(result)
+
+	case "edit_replace", "edit_insert", "edit_delete", "edit_replace_body", "edit_set_doc",
+		"edit_insert_statement", "edit_replace_statement", "edit_delete_statement":
+		var req parser.EditRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return mcpToolResult{}, fmt.Errorf("invalid arguments: %v", err)
+		}
+		req.EditType = strings.ReplaceAll(strings.TrimPrefix(name, "edit_"), "_", "-")
+		before, err := os.ReadFile(req.Path)
+		if err != nil {
+			return mcpToolResult{}, fmt.Errorf("failed to read file: %v", err)
+		}
+		edited, err := execute(Command{Operation: "edit", File: req.Path, Edit: &req})
+		if err != nil {
+			return mcpToolResult{}, err
+		}
+		after, err := os.ReadFile(req.Path)
+		if err != nil {
+			return mcpToolResult{}, fmt.Errorf("failed to read edited file: %v", err)
+		}
+		result := mcpEditResult{
+			Success: true,
+			Path:    req.Path,
+			Diff:    parser.UnifiedDiff(req.Path, string(before), string(after)),
+			Content: string(after),
+
+			ResolvedSymbol:        edited.(parser.EditResult).ResolvedSymbol,
+			BrokenImplementations: edited.(parser.EditResult).BrokenImplementations,
+		}
+		text := result.Diff
+		if text == "" {
+			text = "No changes"
+		}
+		if result.ResolvedSymbol != "" {
+			text = fmt.Sprintf("Edited %s in place of %s\n%s", result.ResolvedSymbol, req.Symbol, text)
+		}
+		if len(result.BrokenImplementations) > 0 {
+			text += fmt.Sprintf("\nNo longer satisfy the interface: %s", strings.Join(result.BrokenImplementations, ", "))
+		}
+		return mcpToolResult{
+			Content:           []mcpContent{{Type: "text", Text: text}},
+			StructuredContent: result,
+		}, nil
+
+	case "create_file":
+		var req parser.CreateRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return mcpToolResult{}, fmt.Errorf("invalid arguments: %v", err)
+		}
+		before, _ := os.ReadFile(req.Path)
+		created, err := execute(Command{Operation: "create", File: req.Path, Create: &req})
+		if err != nil {
+			return mcpToolResult{}, err
+		}
+		content := created.(parser.EditResult).Content
+		result := mcpEditResult{
+			Success: true,
+			Path:    req.Path,
+			Diff:    parser.UnifiedDiff(req.Path, string(before), content),
+			Content: content,
+
+			UnresolvedImports: created.(parser.EditResult).UnresolvedImports,
+		}
+		text := result.Diff
+		if len(result.UnresolvedImports) > 0 {
+			text += fmt.Sprintf("\nNo import found for: %s", strings.Join(result.UnresolvedImports, ", "))
+		}
+		return mcpToolResult{
+			Content:           []mcpContent{{Type: "text", Text: text}},
+			StructuredContent: result,
+		}, nil
+	case "find_references":
+		var req parser.ReferencesRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return mcpToolResult{}, fmt.Errorf("invalid arguments: %v", err)
+		}
+		result, err := execute(Command{Operation: "references", File: req.Path, References: &req})
+		if err != nil {
+			return mcpToolResult{}, err
+		}
+		refs := result.(parser.ReferencesResult)
+		text := fmt.Sprintf("%d references to %s\n", len(refs.References), refs.Symbol)
+		for _, ref := range refs.References {
+			text += fmt.Sprintf("%s:%d:%d %s %s\n", ref.Path, ref.Line, ref.Column, ref.Kind, ref.Enclosing)
+		}
+		if len(refs.Incomplete) > 0 {
+			text += fmt.Sprintf("Could not load, references may be missing: %s\n", strings.Join(refs.Incomplete, ", "))
+		}
+		return mcpToolResult{
+			Content:           []mcpContent{{Type: "text", Text: text}},
+			StructuredContent: refs,
+		}, nil
+
+	case "rename":
+		var req parser.RenameRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return mcpToolResult{}, fmt.Errorf("invalid arguments: %v", err)
+		}
+		result, err := execute(Command{Operation: "rename", File: req.Path, Rename: &req})
+		if err != nil {
+			return mcpToolResult{}, err
+		}
+		renamed := result.(parser.RenameResult)
+		text := fmt.Sprintf("Renamed %s to %s (%d references)\n", renamed.Symbol, req.NewName, renamed.References)
+		if len(renamed.Renamed) > 0 {
+			text += fmt.Sprintf("Also renamed: %s\n", strings.Join(renamed.Renamed, ", "))
+		}
+		for _, f := range renamed.Files {
+			text += f.Diff
+		}
+		return mcpToolResult{
+			Content:           []mcpContent{{Type: "text", Text: text}},
+			StructuredContent: renamed,
+		}, nil
+
+	case "move":
+		var req parser.MoveRequest
+		if err := json.Unmarshal(args, &req); err != nil {
+			return mcpToolResult{}, fmt.Errorf("invalid arguments: %v", err)
+		}
+		result, err := execute(Command{Operation: "move", File: req.Path, Move: &req})
+		if err != nil {
+			return mcpToolResult{}, err
+		}
+		moved := result.(parser.MoveResult)
+		text := "Moved " + strings.Join(moved.Moved, ", ") + "\n"
+		for _, f := range moved.Files {
+			text += f.Diff
+		}
+		if len(moved.UnresolvedImports) > 0 {
+			text += fmt.Sprintf("\nNo import found for: %s", strings.Join(moved.UnresolvedImports, ", "))
+		}
+		return mcpToolResult{
+			Content:           []mcpContent{{Type: "text", Text: text}},
+			StructuredContent: moved,
+		}, nil
+	}
+
+	return mcpToolResult{}, fmt.Errorf("%w: %s", errUnknownTool, name)
+}
+
+// structuredResult returns v both as structured content and as JSON text
+// for clients that only read text content
+func structuredResult(v interface{}) (mcpToolResult, error) {
+	text, err := json.Marshal(v)
+	if err != nil {
+		return mcpToolResult{}, err
+	}
+	return mcpToolResult{
+		Content:           []mcpContent{{Type: "text", Text: string(text)}},
+		StructuredContent: v,
+	}, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rosen/go-parser/parser"
+)
+
+// mcpClient is a minimal MCP client talking to an in-process server
+type mcpClient struct {
+	t      *testing.T
+	in     io.WriteCloser
+	out    *bufio.Scanner
+	nextID int
+	done   chan error
+}
+
+func newMCPClient(t *testing.T) *mcpClient {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &mcpClient{t: t, in: clientOut, out: bufio.NewScanner(clientIn), done: make(chan error, 1)}
+	c.out.Buffer(make([]byte, 1024*1024), 1024*1024)
+	go func() {
+		err := serveMCP(serverIn, serverOut)
+		serverOut.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() {
+		c.in.Close()
+		if err := <-c.done; err != nil {
+			t.Errorf("serveMCP failed: %v", err)
+		}
+	})
+	return c
+}
+
+// call sends a request and decodes the result into v
+func (c *mcpClient) call(method string, params interface{}, v interface{}) *rpcError {
+	c.t.Helper()
+	c.nextID++
+	msg, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := c.in.Write(append(msg, '\n')); err != nil {
+		c.t.Fatal(err)
+	}
+	if !c.out.Scan() {
+		c.t.Fatalf("No response to %s: %v", method, c.out.Err())
+	}
+	var resp struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(c.out.Bytes(), &resp); err != nil {
+		c.t.Fatalf("Invalid response %q: %v", c.out.Text(), err)
+	}
+	if resp.ID != c.nextID {
+		c.t.Fatalf("Response ID = %d, want %d", resp.ID, c.nextID)
+	}
+	if resp.Error == nil && v != nil {
+		if err := json.Unmarshal(resp.Result, v); err != nil {
+			c.t.Fatalf("Invalid result %s: %v", resp.Result, err)
+		}
+	}
+	return resp.Error
+}
+
+// notify sends a notification, which gets no response
+func (c *mcpClient) notify(method string) {
+	msg, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "method": method})
+	if _, err := c.in.Write(append(msg, '\n')); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func TestMCP(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "service.go")
+	content := `package test
+
+// Service processes requests
+type Service struct {
+	Name string
+}
+
+// Process handles data
+func (s *Service) Process() error {
+	return nil
+}
+
+func Unused() {}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	c := newMCPClient(t)
+
+	var initResult struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		Capabilities    struct {
+			Tools *struct{} `json:"tools"`
+		} `json:"capabilities"`
+	}
+	if err := c.call("initialize", map[string]interface{}{
+		"protocolVersion": "2024-11-05",
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": "test", "version": "0"},
+	}, &initResult); err != nil {
+		t.Fatalf("initialize failed: %s", err.Message)
+	}
+	if initResult.ProtocolVersion != "2024-11-05" || initResult.Capabilities.Tools == nil {
+		t.Errorf("initialize result = %+v", initResult)
+	}
+	c.notify("notifications/initialized")
+
+	var list struct {
+		Tools []mcpTool `json:"tools"`
+	}
+	if err := c.call("tools/list", nil, &list); err != nil {
+		t.Fatalf("tools/list failed: %s", err.Message)
+	}
+	schemas := make(map[string]map[string]interface{})
+	for _, tool := range list.Tools {
+		schemas[tool.Name] = tool.InputSchema
+	}
+	for _, name := range []string{"parse", "get_symbol", "skeleton", "context", "edit_replace", "edit_insert", "edit_delete", "edit_replace_body", "edit_set_doc", "edit_insert_statement", "edit_replace_statement", "edit_delete_statement", "create_file", "move", "rename", "find_references"} {
+		if schemas[name] == nil {
+			t.Errorf("Tool %s not listed", name)
+		}
+	}
+	insertProps := schemas["edit_insert"]["properties"].(map[string]interface{})
+	position := insertProps["Insert"].(map[string]interface{})["properties"].(map[string]interface{})["Position"].(map[string]interface{})
+	if position["type"] != "string" || !strings.Contains(position["description"].(string), "after-imports") {
+		t.Errorf("Insert.Position schema = %v", position)
+	}
+	statementProps := schemas["edit_insert_statement"]["properties"].(map[string]interface{})
+	position = statementProps["Statement"].(map[string]interface{})["properties"].(map[string]interface{})["Position"].(map[string]interface{})
+	if enum, ok := position["enum"].([]interface{}); !ok || len(enum) != 2 {
+		t.Errorf("Statement.Position schema = %v", position)
+	}
+	if _, ok := schemas["edit_delete"]["properties"].(map[string]interface{})["Content"]; ok {
+		t.Error("edit_delete schema should not accept Content")
+	}
+
+	var parsed mcpToolResult
+	if err := c.call("tools/call", map[string]interface{}{
+		"name":      "parse",
+		"arguments": map[string]interface{}{"file": path},
+	}, &parsed); err != nil {
+		t.Fatalf("parse failed: %s", err.Message)
+	}
+	if parsed.IsError || !strings.Contains(parsed.Content[0].Text, `"Process"`) {
+		t.Errorf("parse result = %+v", parsed)
+	}
+
+	var symbol struct {
+		StructuredContent parser.GetResult `json:"structuredContent"`
+	}
+	if err := c.call("tools/call", map[string]interface{}{
+		"name":      "get_symbol",
+		"arguments": map[string]interface{}{"file": path, "symbol": "Service.Process"},
+	}, &symbol); err != nil {
+		t.Fatalf("get_symbol failed: %s", err.Message)
+	}
+	if !strings.HasPrefix(symbol.StructuredContent.Source, "// Process handles data\nfunc (s *Service) Process() error {") {
+		t.Errorf("get_symbol source = %q", symbol.StructuredContent.Source)
+	}
+
+	var edited struct {
+		IsError           bool          `json:"isError"`
+		StructuredContent mcpEditResult `json:"structuredContent"`
+	}
+	if err := c.call("tools/call", map[string]interface{}{
+		"name":      "edit_delete",
+		"arguments": map[string]interface{}{"Path": path, "Symbol": "Unused"},
+	}, &edited); err != nil {
+		t.Fatalf("edit_delete failed: %s", err.Message)
+	}
+	if edited.IsError || !strings.Contains(edited.StructuredContent.Diff, "-func Unused() {}") {
+		t.Errorf("edit_delete result = %+v", edited)
+	}
+
+	var failed mcpToolResult
+	if err := c.call("tools/call", map[string]interface{}{
+		"name":      "edit_replace",
+		"arguments": map[string]interface{}{"Path": path, "Symbol": "Missing", "Content": "func Missing() {}"},
+	}, &failed); err != nil {
+		t.Fatalf("edit_replace failed at the protocol level: %s", err.Message)
+	}
+	if !failed.IsError || !strings.Contains(failed.Content[0].Text, "Symbol not found") {
+		t.Errorf("edit_replace result = %+v, want tool error", failed)
+	}
+
+	if err := c.call("tools/call", map[string]interface{}{"name": "nope"}, nil); err == nil || err.Code != rpcInvalidParams {
+		t.Errorf("unknown tool error = %+v, want invalid params", err)
+	}
+}
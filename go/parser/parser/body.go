@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"strings"
+)
+
+// editBody replaces the statements of a function or method, leaving its doc
+// comment, receiver and signature exactly as they were
+func editBody(fset *token.FileSet, file *ast.File, content []byte, req EditRequest) EditResult {
+	body := strings.Trim(req.Content, "\n")
+	if err := parseStatements(file.Name.Name, body); err != nil {
+		return failure(err)
+	}
+
+	decl, resolved, findErr := findTarget(fset, file, req.Symbol, req.FuzzyMatch)
+	if findErr != nil {
+		return failure(findErr)
+	}
+	fn, ok := decl.(*ast.FuncDecl)
+	if !ok {
+		return failure(newError(CodeInvalidRequest, &ErrorDetails{Symbol: req.Symbol}, "Cannot replace the body of %s: not a function or method", req.Symbol))
+	}
+	if fn.Body == nil {
+		return failure(newError(CodeInvalidRequest, &ErrorDetails{Symbol: req.Symbol}, "Cannot replace the body of %s: it is declared without one", req.Symbol))
+	}
+
+	lbrace := fset.Position(fn.Body.Lbrace).Offset
+	rbrace := fset.Position(fn.Body.Rbrace).Offset
+	edited := string(content[:lbrace+1]) + "\n" + body + "\n" + string(content[rbrace:])
+
+	formatted, err := format.Source([]byte(edited))
+	if err != nil {
+		return failure(newError(CodeFormatFailed, nil, "Failed to format modified code: %v", err))
+	}
+	return EditResult{
+		Success:        true,
+		Content:        string(formatted),
+		ResolvedSymbol: resolved,
+	}
+}
+
+// parseStatements checks that content is a valid list of statements on its
+// own, so that it cannot close the function it is spliced into
+func parseStatements(pkg, content string) *Error {
+	wrapped := fmt.Sprintf("package %s\nfunc _() {\n%s\n}", pkg, content)
+	parsed, err := parseFile(token.NewFileSet(), "", wrapped)
+	if err != nil {
+		// Report positions relative to the content, not the wrapper added above
+		return newError(CodeContentSyntax, syntaxDetails(err, 2), "Failed to parse new statements: %v", err)
+	}
+	if len(parsed.Decls) != 1 {
+		return newError(CodeContentSyntax, nil, "New content must contain only statements: it closes the function early")
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"strings"
+)
+
+// knownOS and knownArch mirror the GOOS and GOARCH values the go tool
+// recognises in file name suffixes such as _linux.go or _windows_amd64.go
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true,
+	"freebsd": true, "hurd": true, "illumos": true, "ios": true,
+	"js": true, "linux": true, "nacl": true, "netbsd": true,
+	"openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+	"windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true,
+	"armbe": true, "arm64": true, "arm64be": true, "loong64": true,
+	"mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+	"mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+	"ppc64le": true, "riscv": true, "riscv64": true, "s390": true,
+	"s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+}
+
+// headerConstraint returns the build constraint declared in the file header,
+// preferring //go:build over legacy // +build lines
+func headerConstraint(file *ast.File) constraint.Expr {
+	var plus []constraint.Expr
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if constraint.IsGoBuild(c.Text) {
+				if expr, err := constraint.Parse(c.Text); err == nil {
+					return expr
+				}
+			} else if constraint.IsPlusBuild(c.Text) {
+				if expr, err := constraint.Parse(c.Text); err == nil {
+					plus = append(plus, expr)
+				}
+			}
+		}
+	}
+	return and(plus...)
+}
+
+// fileNameConstraint returns the constraint implied by _GOOS, _GOARCH and
+// _GOOS_GOARCH file name suffixes, ignoring the _test suffix
+func fileNameConstraint(name string) constraint.Expr {
+	name = strings.TrimSuffix(name, ".go")
+	name = strings.TrimSuffix(name, "_test")
+	parts := strings.Split(name, "_")
+	// A file named after its GOOS alone, such as linux.go, has no constraint
+	if len(parts) < 2 {
+		return nil
+	}
+	n := len(parts)
+	if n >= 3 && knownOS[parts[n-2]] && knownArch[parts[n-1]] {
+		return and(&constraint.TagExpr{Tag: parts[n-2]}, &constraint.TagExpr{Tag: parts[n-1]})
+	}
+	if knownOS[parts[n-1]] || knownArch[parts[n-1]] {
+		return &constraint.TagExpr{Tag: parts[n-1]}
+	}
+	return nil
+}
+
+// fileConstraint returns the full build constraint expression of a file,
+// combining its header with its file name, or "" if it is unconstrained
+func fileConstraint(name string, file *ast.File) string {
+	expr := and(headerConstraint(file), fileNameConstraint(name))
+	if expr == nil {
+		return ""
+	}
+	return expr.String()
+}
+
+// and combines constraint expressions, skipping nil entries
+func and(exprs ...constraint.Expr) constraint.Expr {
+	var result constraint.Expr
+	for _, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+		if result == nil {
+			result = expr
+		} else {
+			result = &constraint.AndExpr{X: result, Y: expr}
+		}
+	}
+	return result
+}
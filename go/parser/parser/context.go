@@ -0,0 +1,295 @@
+package parser
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ContextRequest represents a request for the declarations a symbol depends on
+type ContextRequest struct {
+	Path   string // File declaring the target symbol
+	Symbol string // Name, or Type.Method for methods
+	Budget int    `json:",omitempty"` // Maximum total size of the sources in bytes, or 0 for no limit
+}
+
+// ContextEntry is one declaration in a context bundle
+type ContextEntry struct {
+	Symbol     string // Qualified name of the declaration
+	Kind       string // Kind of the declaration, as reported by Parse
+	Path       string // File declaring it
+	Source     string // Full source for the target, the signature for functions and methods otherwise
+	Signature  bool   `json:",omitempty"` // Whether the body was elided from Source
+	References int    `json:",omitempty"` // Number of references from the target
+	StartLine  int    // 1-based line where Source starts
+	EndLine    int    // 1-based line where Source ends
+}
+
+// ContextResult contains the target symbol and the package declarations it
+// references, ranked by relevance
+type ContextResult struct {
+	Success bool           // Whether the bundle was built
+	Error   string         // Error message if unsuccessful
+	Code    ErrorCode      `json:",omitempty"` // Stable error code if unsuccessful
+	Details *ErrorDetails  `json:",omitempty"` // Structured error details, such as candidate symbols
+	Entries []ContextEntry // The target first, then referenced declarations by relevance
+	Size    int            // Total size of the sources in bytes
+	Omitted []string       `json:",omitempty"` // Referenced declarations left out to fit the budget
+}
+
+// kindRank orders declarations that are referenced equally often, putting
+// the types and values a function works with before other functions
+var kindRank = map[string]int{
+	"struct": 0, "interface": 0, "type": 0,
+	"constant": 1, "variable": 2,
+	"function": 3, "method": 4,
+}
+
+// packageDecl locates a package-level declaration
+type packageDecl struct {
+	symbol string
+	path   string
+}
+
+// Context returns the target symbol in full followed by the signatures of the
+// declarations in its package that it references, ranked by how often they
+// are referenced and kept within the requested size budget. The target is
+// always included, even when it alone exceeds the budget.
+func Context(req ContextRequest) ContextResult {
+	if req.Path == "" || req.Symbol == "" {
+		return contextFailure(newError(CodeInvalidRequest, nil, "Path and Symbol are required"))
+	}
+	if req.Budget < 0 {
+		return contextFailure(newError(CodeInvalidRequest, nil, "Budget must not be negative"))
+	}
+
+	target, err := os.ReadFile(req.Path)
+	if err != nil {
+		code := CodeReadFailed
+		if errors.Is(err, fs.ErrNotExist) {
+			code = CodeFileNotFound
+		}
+		return contextFailure(newError(code, nil, "Failed to read file: %v", err))
+	}
+	fset := token.NewFileSet()
+	file, err := parseFile(fset, req.Path, target)
+	if err != nil {
+		return contextFailure(newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse file: %v", err))
+	}
+	sel, selErr := selectSymbol(fset, file, req.Symbol)
+	if selErr != nil {
+		return contextFailure(selErr)
+	}
+
+	contents, decls, methods, ctxErr := packageDecls(fset, req.Path, file, target)
+	if ctxErr != nil {
+		return contextFailure(ctxErr)
+	}
+
+	entry := func(d packageDecl, signature bool) (ContextEntry, *Error) {
+		got := GetSource(GetRequest{Path: d.path, Symbol: d.symbol, SignatureOnly: signature}, contents[d.path])
+		if !got.Success {
+			return ContextEntry{}, &Error{Code: got.Code, Message: got.Error, Details: got.Details}
+		}
+		return ContextEntry{
+			Symbol:    got.Symbol,
+			Kind:      got.Kind,
+			Path:      d.path,
+			Source:    got.Source,
+			Signature: signature && (got.Kind == "function" || got.Kind == "method"),
+			StartLine: got.StartLine,
+			EndLine:   got.EndLine,
+		}, nil
+	}
+
+	first, entryErr := entry(packageDecl{symbol: sel.name, path: req.Path}, false)
+	if entryErr != nil {
+		return contextFailure(entryErr)
+	}
+	result := ContextResult{Success: true, Entries: []ContextEntry{first}, Size: len(first.Source)}
+
+	counts := references(file, sel.node, decls, methods)
+	delete(counts, sel.name)
+	var ranked []ContextEntry
+	for symbol, count := range counts {
+		e, entryErr := entry(decls[symbol], true)
+		if entryErr != nil {
+			return contextFailure(entryErr)
+		}
+		e.References = count
+		ranked = append(ranked, e)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.References != b.References {
+			return a.References > b.References
+		}
+		if kindRank[a.Kind] != kindRank[b.Kind] {
+			return kindRank[a.Kind] < kindRank[b.Kind]
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	// Smaller declarations further down the ranking may still fit once a
+	// larger one has been left out
+	for _, e := range ranked {
+		if req.Budget > 0 && result.Size+len(e.Source) > req.Budget {
+			result.Omitted = append(result.Omitted, e.Symbol)
+			continue
+		}
+		result.Entries = append(result.Entries, e)
+		result.Size += len(e.Source)
+	}
+	logger.Debug("built context", "path", req.Path, "symbol", sel.name, "entries", len(result.Entries), "omitted", len(result.Omitted), "size", result.Size)
+	return result
+}
+
+// packageDecls parses the files of the package containing path and indexes
+// its declarations by name, with methods by Type.Method. methods maps each
+// method name to the qualified names of the methods called that.
+func packageDecls(fset *token.FileSet, path string, file *ast.File, content []byte) (map[string][]byte, map[string]packageDecl, map[string][]string, *Error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	candidates, err := packageFiles(dir, PackageOptions{IncludeTests: strings.HasSuffix(base, "_test.go")})
+	if err != nil {
+		return nil, nil, nil, newError(CodeReadFailed, nil, "Failed to read package directory: %v", err)
+	}
+
+	contents := map[string][]byte{path: content}
+	files := map[string]*ast.File{path: file}
+	for _, candidate := range candidates {
+		p := filepath.Join(dir, candidate.name)
+		if candidate.name == base {
+			continue
+		}
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return nil, nil, nil, newError(CodeReadFailed, nil, "Failed to read file: %v", err)
+		}
+		f, err := parseFile(fset, p, src)
+		if err != nil {
+			return nil, nil, nil, newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse file: %v", err)
+		}
+		// External test packages share the directory but not the scope
+		if f.Name.Name != file.Name.Name {
+			continue
+		}
+		contents[p], files[p] = src, f
+	}
+
+	decls := make(map[string]packageDecl)
+	methods := make(map[string][]string)
+	add := func(symbol, p string) {
+		if _, ok := decls[symbol]; !ok {
+			decls[symbol] = packageDecl{symbol: symbol, path: p}
+		}
+	}
+	for p, f := range files {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil || len(d.Recv.List) == 0 {
+					add(d.Name.Name, p)
+					continue
+				}
+				symbol := receiverType(d.Recv.List[0].Type) + "." + d.Name.Name
+				add(symbol, p)
+				methods[d.Name.Name] = append(methods[d.Name.Name], symbol)
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						add(s.Name.Name, p)
+					case *ast.ValueSpec:
+						for _, name := range s.Names {
+							if name.Name != "_" {
+								add(name.Name, p)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return contents, decls, methods, nil
+}
+
+// references counts the identifiers in node that refer to package-level
+// declarations. Local names shadowing a declaration are not counted, and a
+// selector x.M counts every method named M since its receiver is unknown
+// without type information.
+func references(file *ast.File, node ast.Node, decls map[string]packageDecl, methods map[string][]string) map[string]int {
+	counts := make(map[string]int)
+	skip := make(map[*ast.Ident]bool)
+	imports := make(map[string]bool)
+	for _, imp := range file.Imports {
+		name := strings.Trim(imp.Path.Value, `"`)
+		name = name[strings.LastIndex(name, "/")+1:]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = true
+	}
+
+	if fn, ok := node.(*ast.FuncDecl); ok {
+		skip[fn.Name] = true
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			skip[n.Sel] = true
+			if x, ok := n.X.(*ast.Ident); ok && x.Obj == nil && imports[x.Name] {
+				// A qualified identifier from another package
+				skip[x] = true
+				return false
+			}
+			for _, symbol := range methods[n.Sel.Name] {
+				counts[symbol]++
+			}
+		case *ast.CompositeLit:
+			switch n.Type.(type) {
+			case *ast.MapType, *ast.ArrayType:
+			default:
+				// Keys of struct literals name fields, not declarations
+				for _, elt := range n.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						if key, ok := kv.Key.(*ast.Ident); ok {
+							skip[key] = true
+						}
+					}
+				}
+			}
+		case *ast.Ident:
+			if skip[n] {
+				return true
+			}
+			if n.Obj != nil && file.Scope.Lookup(n.Name) != n.Obj {
+				// Resolved to a local declaration
+				return true
+			}
+			if _, ok := decls[n.Name]; ok {
+				counts[n.Name]++
+			}
+		}
+		return true
+	})
+	return counts
+}
+
+// contextFailure converts an error into a failed ContextResult
+func contextFailure(err *Error) ContextResult {
+	return ContextResult{
+		Success: false,
+		Error:   err.Message,
+		Code:    err.Code,
+		Details: err.Details,
+	}
+}
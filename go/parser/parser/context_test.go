@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"service.go": `package svc
+
+import "strings"
+
+// Service processes requests
+type Service struct {
+	Name string
+}
+
+// Process handles data
+func (s *Service) Process(input string) error {
+	limit := MaxSize
+	if len(input) > limit {
+		return ErrTooLarge
+	}
+	s.validate(strings.TrimSpace(input))
+	s.validate(input)
+	return save(Record{Name: s.Name})
+}
+
+func (s *Service) validate(string) {}
+
+func Unrelated() {}
+`,
+		"store.go": `package svc
+
+import "errors"
+
+// MaxSize limits the input
+const MaxSize = 1024
+
+var ErrTooLarge = errors.New("too large")
+
+// Record is a stored item
+type Record struct {
+	Name string
+}
+
+// save stores a record
+func save(r Record) error {
+	return nil
+}
+
+// Name is a package-level name that struct keys must not pick up
+func Name() {}
+`,
+		"service_test.go": `package svc_test
+
+func helper() {}
+`,
+	})
+	path := filepath.Join(dir, "service.go")
+
+	result := Context(ContextRequest{Path: path, Symbol: "Service.Process"})
+	if !result.Success {
+		t.Fatalf("Context() failed: %s", result.Error)
+	}
+
+	var got []string
+	for _, e := range result.Entries {
+		got = append(got, e.Symbol)
+	}
+	want := []string{"Service.Process", "Service.validate", "Record", "Service", "MaxSize", "ErrTooLarge", "save"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Context() entries = %v, want %v", got, want)
+	}
+
+	target := result.Entries[0]
+	if target.Signature || !strings.HasPrefix(target.Source, "// Process handles data\n") || !strings.Contains(target.Source, "return save(") {
+		t.Errorf("Target not returned in full: %+v", target)
+	}
+	for _, e := range result.Entries[1:] {
+		if strings.Contains(e.Source, "return nil") {
+			t.Errorf("%s returned with its body:\n%s", e.Symbol, e.Source)
+		}
+		if e.Symbol == "save" && (!e.Signature || e.Source != "// save stores a record\nfunc save(r Record) error" || e.Path != filepath.Join(dir, "store.go")) {
+			t.Errorf("save entry = %+v", e)
+		}
+		if e.Symbol == "Service.validate" && e.References != 2 {
+			t.Errorf("Service.validate references = %d, want 2", e.References)
+		}
+	}
+
+	size := 0
+	for _, e := range result.Entries {
+		size += len(e.Source)
+	}
+	if result.Size != size {
+		t.Errorf("Context() size = %d, want %d", result.Size, size)
+	}
+
+	// A budget keeps the target and the best-ranked entries that fit
+	budget := len(target.Source) + len("func (s *Service) validate(string)")
+	limited := Context(ContextRequest{Path: path, Symbol: "Service.Process", Budget: budget})
+	if !limited.Success || len(limited.Entries) != 2 || limited.Entries[1].Symbol != "Service.validate" || limited.Size > budget {
+		t.Errorf("Context() with budget %d = %+v", budget, limited)
+	}
+	if len(limited.Omitted) != len(want)-2 {
+		t.Errorf("Context() omitted = %v", limited.Omitted)
+	}
+
+	// The target is returned even when it alone exceeds the budget
+	tiny := Context(ContextRequest{Path: path, Symbol: "Process", Budget: 10})
+	if !tiny.Success || len(tiny.Entries) != 1 || tiny.Entries[0].Symbol != "Service.Process" {
+		t.Errorf("Context() with tiny budget = %+v", tiny)
+	}
+
+	if missing := Context(ContextRequest{Path: path, Symbol: "Missing"}); missing.Code != CodeSymbolNotFound {
+		t.Errorf("Context() for missing symbol code = %q", missing.Code)
+	}
+}
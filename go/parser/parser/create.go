@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// CreateRequest represents a request to create a new Go file
+type CreateRequest struct {
+	Path         string // File to create
+	Content      string `json:",omitempty"` // Declarations of the new file, with or without imports; missing imports are added
+	Package      string `json:",omitempty"` // Package name, inferred from the other files in the directory when empty
+	ExternalTest bool   `json:",omitempty"` // Place a _test.go file in the external test package, such as foo_test
+	Overwrite    bool   `json:",omitempty"` // Replace the file if it already exists
+}
+
+// Create writes a new Go file holding the declarations of the request under
+// the package clause of its directory, with the imports its code needs
+func Create(req CreateRequest) EditResult {
+	if req.Path == "" || !strings.HasSuffix(req.Path, ".go") {
+		return failure(newError(CodeInvalidRequest, nil, "Path is required and must name a .go file"))
+	}
+	if req.ExternalTest && !strings.HasSuffix(req.Path, "_test.go") {
+		return failure(newError(CodeInvalidRequest, nil, "ExternalTest requires a _test.go file"))
+	}
+	if req.Package != "" && !token.IsIdentifier(req.Package) {
+		return failure(newError(CodeInvalidRequest, nil, "Invalid Package: %q is not a Go identifier", req.Package))
+	}
+	if _, err := os.Stat(req.Path); err == nil && !req.Overwrite {
+		return failure(newError(CodeFileExists, nil, "File already exists: %s (set Overwrite to replace it)", req.Path))
+	}
+
+	dir, base := filepath.Split(req.Path)
+	if dir == "" {
+		dir = "."
+	}
+	pkg := req.Package
+	if pkg == "" {
+		// A file being replaced still tells which package it belongs to
+		skip := base
+		if req.Overwrite {
+			skip = ""
+		}
+		var inferErr *Error
+		if pkg, inferErr = inferPackage(dir, skip); inferErr != nil {
+			return failure(inferErr)
+		}
+		if req.ExternalTest {
+			pkg += "_test"
+		}
+	}
+
+	src := fmt.Sprintf("package %s\n\n%s\n", pkg, strings.TrimSpace(req.Content))
+	if _, err := parseFile(token.NewFileSet(), req.Path, src); err != nil {
+		// Report positions relative to the content, not the package clause added above
+		return failure(newError(CodeContentSyntax, syntaxDetails(err, 2), "Failed to parse new content: %v", err))
+	}
+	ctx, ctxErr := packageImportContext(dir, pkg, base)
+	if ctxErr != nil {
+		return failure(ctxErr)
+	}
+	content, unresolved, fixErr := fixImports([]byte(src), ctx)
+	if fixErr != nil {
+		return failure(fixErr)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return failure(newError(CodeWriteFailed, nil, "Failed to create directory: %v", err))
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !req.Overwrite {
+		// Another writer may have created the file since it was checked
+		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+	f, err := os.OpenFile(req.Path, flags, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return failure(newError(CodeFileExists, nil, "File already exists: %s (set Overwrite to replace it)", req.Path))
+	}
+	if err == nil {
+		_, err = f.Write(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return failure(newError(CodeWriteFailed, nil, "Failed to write file: %v", err))
+	}
+	logger.Debug("created file", "path", req.Path, "package", pkg, "bytes", len(content))
+
+	return EditResult{
+		Success:           true,
+		Content:           string(content),
+		UnresolvedImports: unresolved,
+	}
+}
+
+// inferPackage returns the name of the package in dir, ignoring skip. Test
+// files only decide when there are no other files, and an empty directory
+// is named after itself.
+func inferPackage(dir, skip string) (string, *Error) {
+	candidates, err := packageFiles(dir, PackageOptions{IncludeTests: true})
+	if err != nil && !os.IsNotExist(err) {
+		return "", newError(CodeReadFailed, nil, "Failed to read package directory: %v", err)
+	}
+
+	found := make(map[string]bool)
+	fromTests := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, candidate := range candidates {
+		if candidate.name == skip {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, candidate.name), nil, parser.PackageClauseOnly)
+		if err != nil {
+			continue
+		}
+		name := f.Name.Name
+		if strings.HasSuffix(candidate.name, "_test.go") {
+			fromTests[strings.TrimSuffix(name, "_test")] = true
+			continue
+		}
+		// Package documentation files do not belong to the package
+		if name != "documentation" {
+			found[name] = true
+		}
+	}
+	if len(found) == 0 {
+		found = fromTests
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	switch len(names) {
+	case 1:
+		return names[0], nil
+	case 0:
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", newError(CodeInvalidRequest, nil, "Cannot infer the package name: set Package")
+		}
+		name := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, filepath.Base(abs))
+		if !token.IsIdentifier(name) {
+			return "", newError(CodeInvalidRequest, nil, "Cannot infer the package name from directory %s: set Package", abs)
+		}
+		return name, nil
+	}
+	return "", newError(CodeInvalidRequest, nil, "Directory holds several packages (%s): set Package", strings.Join(names, ", "))
+}
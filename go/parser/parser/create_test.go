@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreate(t *testing.T) {
+	siblings := map[string]string{
+		"service.go": `package svc
+
+import (
+	"fmt"
+
+	yaml "example.com/yaml/v3"
+)
+
+var defaultName = "svc"
+
+func Describe() string { return fmt.Sprint(yaml.Version) }
+`,
+		"service_test.go": "package svc\n",
+	}
+
+	tests := []struct {
+		name           string
+		files          map[string]string
+		req            CreateRequest
+		wantCode       ErrorCode
+		want           string
+		wantUnresolved []string
+	}{
+		{
+			name:  "package inferred with imports added",
+			files: siblings,
+			req: CreateRequest{Path: "extra.go", Content: `// Load reads a config.
+func Load(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", defaultName, err)
+	}
+	return yaml.Parse(strings.TrimSpace(string(data)))
+}`},
+			want: `package svc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	yaml "example.com/yaml/v3"
+)
+
+// Load reads a config.
+func Load(path string) (*yaml.Node, error) {`,
+		},
+		{
+			name:  "unused explicit import dropped",
+			files: siblings,
+			req:   CreateRequest{Path: "extra.go", Content: "import (\n\t\"bytes\"\n\t\"io\"\n)\n\nvar r io.Reader"},
+			want:  "package svc\n\nimport (\n\t\"io\"\n)\n\nvar r io.Reader\n",
+		},
+		{
+			name:  "external test package",
+			files: siblings,
+			req:   CreateRequest{Path: "extra_test.go", ExternalTest: true, Content: "func TestX(t *testing.T) {}"},
+			want:  "package svc_test\n\nimport (\n\t\"testing\"\n)\n\nfunc TestX(t *testing.T) {}\n",
+		},
+		{
+			name:  "internal test package",
+			files: siblings,
+			req:   CreateRequest{Path: "extra_test.go"},
+			want:  "package svc\n",
+		},
+		{
+			name:  "only test files",
+			files: map[string]string{"a_test.go": "package lib_test\n"},
+			req:   CreateRequest{Path: "lib.go"},
+			want:  "package lib\n",
+		},
+		{
+			name:           "ambiguous package is unresolved",
+			files:          siblings,
+			req:            CreateRequest{Path: "extra.go", Content: "var n = rand.Int()"},
+			want:           "package svc\n\nvar n = rand.Int()\n",
+			wantUnresolved: []string{"rand"},
+		},
+		{
+			name:  "versioned standard library import",
+			files: siblings,
+			req:   CreateRequest{Path: "extra.go", Content: "import \"math/rand/v2\"\n\nvar n = rand.IntN(6)"},
+			want:  "package svc\n\nimport \"math/rand/v2\"\n\nvar n = rand.IntN(6)\n",
+		},
+		{
+			name:  "explicit package",
+			files: siblings,
+			req:   CreateRequest{Path: "main.go", Package: "main", Content: "func main() {}"},
+			want:  "package main\n\nfunc main() {}\n",
+		},
+		{
+			name:     "several packages",
+			files:    map[string]string{"a.go": "package a\n", "b.go": "package b\n"},
+			req:      CreateRequest{Path: "c.go"},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "existing file",
+			files:    siblings,
+			req:      CreateRequest{Path: "service.go", Content: "func X() {}"},
+			wantCode: CodeFileExists,
+		},
+		{
+			name:  "overwrite",
+			files: siblings,
+			req:   CreateRequest{Path: "service.go", Content: "func X() {}", Overwrite: true},
+			want:  "package svc\n\nfunc X() {}\n",
+		},
+		{
+			name:     "content syntax",
+			files:    siblings,
+			req:      CreateRequest{Path: "extra.go", Content: "func {"},
+			wantCode: CodeContentSyntax,
+		},
+		{
+			name:     "external test needs test file",
+			files:    siblings,
+			req:      CreateRequest{Path: "extra.go", ExternalTest: true},
+			wantCode: CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writePackage(t, tt.files)
+			req := tt.req
+			req.Path = filepath.Join(dir, req.Path)
+			result := Create(req)
+			if result.Code != tt.wantCode {
+				t.Fatalf("Create() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if tt.wantCode != "" {
+				return
+			}
+			if !strings.HasPrefix(result.Content, tt.want) {
+				t.Errorf("Create() content = %q, want prefix %q", result.Content, tt.want)
+			}
+			if !reflect.DeepEqual(result.UnresolvedImports, tt.wantUnresolved) {
+				t.Errorf("Create() unresolved = %v, want %v", result.UnresolvedImports, tt.wantUnresolved)
+			}
+			written, err := os.ReadFile(req.Path)
+			if err != nil || string(written) != result.Content {
+				t.Errorf("File content = %q, want %q (error: %v)", written, result.Content, err)
+			}
+		})
+	}
+}
+
+func TestCreateInNewDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Widgets", "widgets.go")
+	result := Create(CreateRequest{Path: path})
+	if !result.Success || result.Content != "package widgets\n" {
+		t.Errorf("Create() = %+v", result)
+	}
+}
@@ -0,0 +1,221 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffOp is a single line of an edit script
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// UnifiedDiff returns the changes between two versions of a file in unified
+// diff format, or "" if they are identical
+func UnifiedDiff(path string, before, after string) string {
+	if before == after {
+		return ""
+	}
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+
+	for i := 0; i < len(ops); {
+		// Skip to the next change
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are within twice the context of each other
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		end += diffContext
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		// Count line numbers up to the hunk
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:start] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			b.WriteByte('\n')
+		}
+		i = end
+	}
+
+	return b.String()
+}
+
+// hunkRange formats the line range of one side of a hunk
+func hunkRange(line, count int) string {
+	if count == 0 {
+		// An empty range names the line before it
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// splitLines splits text into lines without their terminating newlines
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// maxDiffEdits bounds the edit distance diffLines searches for. The search
+// keeps a trace quadratic in the distance, so beyond it the changed region
+// is reported as replaced wholesale.
+const maxDiffEdits = 1000
+
+// diffLines computes a shortest line edit script with Myers' algorithm
+func diffLines(a, b []string) []diffOp {
+	// Trim the common prefix and suffix to keep the search small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, editScript(midA, midB)...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// editScript returns the edits turning a into b, searching at most
+// maxDiffEdits edits deep before falling back to removing all of a and
+// adding all of b
+func editScript(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	// trace[d][k+d] is the furthest x reached on diagonal k = x-y with d edits
+	var trace [][]int
+	for d := 0; d <= min(n+m, maxDiffEdits); d++ {
+		v := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			switch {
+			case d == 0:
+				x = 0
+			case k == -d || (k != d && trace[d-1][k-1+d-1] < trace[d-1][k+1+d-1]):
+				x = trace[d-1][k+1+d-1] // Add a line of b
+			default:
+				x = trace[d-1][k-1+d-1] + 1 // Remove a line of a
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+d] = x
+			if x >= n && y >= m {
+				return backtrack(append(trace, v), a, b)
+			}
+		}
+		trace = append(trace, v)
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// backtrack walks the trace of editScript back from the end of both
+// sequences and returns the edits in order
+func backtrack(trace [][]int, a, b []string) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		prev, k := trace[d-1], x-y
+		// Repeat the choice editScript made on reaching diagonal k
+		add := k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1])
+		prevK := k - 1
+		if add {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		// The lines matched after the edit
+		snakeX := prevX + 1
+		if add {
+			snakeX = prevX
+		}
+		for x > snakeX {
+			x--
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if add {
+			ops = append(ops, diffOp{'+', b[prevY]})
+		} else {
+			ops = append(ops, diffOp{'-', a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 {
+		x--
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{
+			name:   "identical",
+			before: "a\nb\n",
+			after:  "a\nb\n",
+			want:   "",
+		},
+		{
+			name:   "single change",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			after:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: `--- a/f.go
++++ b/f.go
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+		},
+		{
+			name:   "separate hunks",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			after:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n",
+			want: `--- a/f.go
++++ b/f.go
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`,
+		},
+		{
+			name:   "new file",
+			before: "",
+			after:  "package p\n",
+			want: `--- a/f.go
++++ b/f.go
+@@ -0,0 +1 @@
++package p
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("f.go", tt.before, tt.after); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// lcsLength is the quadratic reference for the number of kept lines
+	lcsLength := func(a, b []string) int {
+		prev := make([]int, len(b)+1)
+		for i := range a {
+			cur := make([]int, len(b)+1)
+			for j := range b {
+				if a[i] == b[j] {
+					cur[j+1] = prev[j] + 1
+				} else {
+					cur[j+1] = max(prev[j+1], cur[j])
+				}
+			}
+			prev = cur
+		}
+		return prev[len(b)]
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		out := make([]string, rng.Intn(12))
+		for i := range out {
+			out[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return out
+	}
+	for i := 0; i < 500; i++ {
+		a, b := lines(), lines()
+		var gotA, gotB []string
+		kept := 0
+		for _, op := range diffLines(a, b) {
+			if op.kind != '+' {
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.line)
+			}
+			if op.kind == ' ' {
+				kept++
+			}
+		}
+		if strings.Join(gotA, ",") != strings.Join(a, ",") || strings.Join(gotB, ",") != strings.Join(b, ",") {
+			t.Fatalf("diffLines(%q, %q) does not reproduce both sides: %q, %q", a, b, gotA, gotB)
+		}
+		if want := lcsLength(a, b); kept != want {
+			t.Fatalf("diffLines(%q, %q) keeps %d lines, want %d", a, b, kept, want)
+		}
+	}
+
+	// Past maxDiffEdits the changed region is replaced as a whole
+	var a, b []string
+	for i := 0; i < maxDiffEdits; i++ {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+	ops := diffLines(a, b)
+	if len(ops) != 2*maxDiffEdits || ops[0].kind != '-' || ops[maxDiffEdits].kind != '+' {
+		t.Errorf("diffLines of %d replaced lines gives %d ops starting %+v", maxDiffEdits, len(ops), ops[0])
+	}
+}
@@ -0,0 +1,185 @@
+package parser
+
+import (
+	"go/ast"
+	"go/format"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// defaultDocWidth is the column doc comments are wrapped at by default
+const defaultDocWidth = 80
+
+// DocConfig contains the options for set-doc operations
+type DocConfig struct {
+	Width      int    `json:",omitempty"` // Maximum length of a comment line including "// ", excluding indentation; 0 for 80, negative to disable wrapping
+	Godoc      bool   `json:",omitempty"` // Require the comment to start with the symbol name and give Deprecated: notes their own paragraph
+	Deprecated string `json:",omitempty"` // Append a "Deprecated: " paragraph with this text
+}
+
+// preformatted matches comment lines that are kept as written: indented
+// code and list items
+var preformatted = regexp.MustCompile(`^(\s|[-*+] |\d+[.)] )`)
+
+// directive matches comment lines addressed to tools rather than readers,
+// such as //go:embed and //export, as go/ast recognizes them
+var directive = regexp.MustCompile(`^//(line |extern |export |[a-z0-9]+:[a-z0-9])`)
+
+// editDoc replaces, adds or removes the doc comment of a declaration, spec
+// or field, leaving the rest of the file and the directives of the comment
+// untouched
+func editDoc(fset *token.FileSet, file *ast.File, content []byte, req EditRequest) EditResult {
+	sel, err := selectSymbol(fset, file, req.Symbol)
+	resolved := ""
+	if err != nil && err.Code == CodeSymbolNotFound && req.FuzzyMatch {
+		if name, ok := fuzzyResolve(fset, file, req.Symbol, kindAll); ok {
+			if sel, err = selectSymbol(fset, file, name); err == nil {
+				resolved = name
+			}
+		}
+	}
+	if err != nil {
+		return failure(err)
+	}
+
+	var config DocConfig
+	if req.Doc != nil {
+		config = *req.Doc
+	}
+	name := sel.name[strings.LastIndex(sel.name, ".")+1:]
+	paragraphs := docParagraphs(req.Content, config)
+	if config.Godoc && len(paragraphs) > 0 && !startsWithName(paragraphs[0], name) {
+		return failure(newError(CodeInvalidRequest, &ErrorDetails{Symbol: sel.name},
+			"Doc comment for %s must start with %q to follow godoc conventions", sel.name, name))
+	}
+
+	// The comment goes on the lines directly above the declaration
+	nodeStart := fset.Position(sel.node.Pos()).Offset
+	lineStart := nodeStart
+	for lineStart > 0 && (content[lineStart-1] == ' ' || content[lineStart-1] == '\t') {
+		lineStart--
+	}
+	if lineStart > 0 && content[lineStart-1] != '\n' {
+		return failure(newError(CodeInvalidRequest, &ErrorDetails{Symbol: sel.name},
+			"Cannot document %s: it shares its line with other code", sel.name))
+	}
+	indent := string(content[lineStart:nodeStart])
+	from := lineStart
+	var directives []string
+	if sel.doc != nil {
+		from = fset.Position(sel.doc.Pos()).Offset
+		for from > 0 && content[from-1] != '\n' {
+			from--
+		}
+		for _, c := range sel.doc.List {
+			if directive.MatchString(c.Text) {
+				directives = append(directives, c.Text)
+			}
+		}
+	}
+
+	var comment strings.Builder
+	width := config.Width
+	if width == 0 {
+		width = defaultDocWidth
+	}
+	for i, paragraph := range paragraphs {
+		if i > 0 {
+			comment.WriteString(indent + "//\n")
+		}
+		for _, line := range wrapParagraph(paragraph, width-len("// ")) {
+			comment.WriteString(strings.TrimRight(indent+"// "+line, " ") + "\n")
+		}
+	}
+	for _, d := range directives {
+		comment.WriteString(indent + d + "\n")
+	}
+
+	edited := string(content[:from]) + comment.String() + string(content[lineStart:])
+	formatted, fmtErr := format.Source([]byte(edited))
+	if fmtErr != nil {
+		return failure(newError(CodeFormatFailed, nil, "Failed to format modified code: %v", fmtErr))
+	}
+	return EditResult{
+		Success:        true,
+		Content:        string(formatted),
+		ResolvedSymbol: resolved,
+	}
+}
+
+// docParagraphs splits doc text into paragraphs of lines, removing comment
+// markers and applying the Deprecated option
+func docParagraphs(text string, config DocConfig) [][]string {
+	var paragraphs [][]string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, current)
+			current = nil
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if trimmed := strings.TrimLeft(line, " \t"); strings.HasPrefix(trimmed, "//") {
+			line = strings.TrimPrefix(strings.TrimPrefix(trimmed, "//"), " ")
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if config.Godoc && strings.HasPrefix(line, "Deprecated:") {
+			// Deprecation notices must start a paragraph to be recognised
+			flush()
+		}
+		current = append(current, line)
+	}
+	flush()
+	if config.Deprecated != "" {
+		paragraphs = append(paragraphs, []string{"Deprecated: " + config.Deprecated})
+	}
+	return paragraphs
+}
+
+// wrapParagraph joins the prose lines of a paragraph and wraps them at width,
+// keeping preformatted lines as they are
+func wrapParagraph(lines []string, width int) []string {
+	var out, words []string
+	flush := func() {
+		line := ""
+		for _, word := range words {
+			if line != "" && width > 0 && len(line)+1+len(word) > width {
+				out = append(out, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		if line != "" {
+			out = append(out, line)
+		}
+		words = nil
+	}
+	for _, line := range lines {
+		if preformatted.MatchString(line) {
+			flush()
+			out = append(out, line)
+			continue
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	flush()
+	return out
+}
+
+// startsWithName reports whether a doc paragraph starts with name, optionally
+// preceded by an article as godoc allows
+func startsWithName(paragraph []string, name string) bool {
+	words := strings.Fields(paragraph[0])
+	if len(words) > 1 && (words[0] == "A" || words[0] == "An" || words[0] == "The") {
+		words = words[1:]
+	}
+	return len(words) > 0 && words[0] == name
+}
@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditSetDoc(t *testing.T) {
+	initial := `package test
+
+// Process handles data.
+func (s *Service) Process() {}
+
+func Run() {}
+
+// Config holds settings.
+type Config struct {
+	// Name is the name.
+	Name string
+	Port int
+}
+
+const (
+	// A is first.
+	A = 1
+	B = 2
+)
+
+var x, y = 1, 2
+
+// data is embedded.
+//
+//go:embed a.txt
+var data string
+
+//go:noinline
+// Foo is not inlined.
+func Foo() {}
+`
+
+	tests := []struct {
+		name     string
+		req      EditRequest
+		wantCode ErrorCode
+		want     string
+		wantNot  string
+	}{
+		{
+			name: "replace method doc",
+			req:  EditRequest{Symbol: "Service.Process", Content: "Process handles data in place."},
+			want: "// Process handles data in place.\nfunc (s *Service) Process() {}\n",
+		},
+		{
+			name: "add function doc",
+			req:  EditRequest{Symbol: "Run", Content: "Run starts the service."},
+			want: "func (s *Service) Process() {}\n\n// Run starts the service.\nfunc Run() {}\n",
+		},
+		{
+			name:    "remove type doc",
+			req:     EditRequest{Symbol: "Config"},
+			want:    "func Run() {}\n\ntype Config struct {",
+			wantNot: "holds settings",
+		},
+		{
+			name: "add field doc",
+			req:  EditRequest{Symbol: "Config.Port", Content: "Port is the port."},
+			want: "\tName string\n\t// Port is the port.\n\tPort int\n",
+		},
+		{
+			name:    "replace grouped const doc",
+			req:     EditRequest{Symbol: "A", Content: "// A is the first value."},
+			want:    "const (\n\t// A is the first value.\n\tA = 1\n",
+			wantNot: "A is first.",
+		},
+		{
+			name: "add grouped const doc",
+			req:  EditRequest{Symbol: "B", Content: "B is second."},
+			want: "\tA = 1\n\t// B is second.\n\tB = 2\n",
+		},
+		{
+			name: "add doc to multi-name var",
+			req:  EditRequest{Symbol: "y", Content: "x and y are coordinates."},
+			want: "// x and y are coordinates.\nvar x, y = 1, 2\n",
+		},
+		{
+			name: "wrap to width",
+			req: EditRequest{Symbol: "Run", Content: "Run starts the service and blocks until it stops.",
+				Doc: &DocConfig{Width: 30}},
+			want: "// Run starts the service and\n// blocks until it stops.\nfunc Run() {}\n",
+		},
+		{
+			name: "paragraphs and lists are preserved",
+			req: EditRequest{Symbol: "Run", Content: "Run starts\nthe service.\n\nSteps:\n  - listen\n  - serve",
+				Doc: &DocConfig{Width: -1}},
+			want: "// Run starts the service.\n//\n// Steps:\n//   - listen\n//   - serve\nfunc Run() {}\n",
+		},
+		{
+			name: "godoc accepts article",
+			req:  EditRequest{Symbol: "Config", Content: "A Config holds settings.", Doc: &DocConfig{Godoc: true}},
+			want: "// A Config holds settings.\ntype Config struct {",
+		},
+		{
+			name:     "godoc requires symbol name",
+			req:      EditRequest{Symbol: "Run", Content: "Starts the service.", Doc: &DocConfig{Godoc: true}},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name: "godoc moves deprecation to its own paragraph",
+			req: EditRequest{Symbol: "Run", Content: "Run starts the service.\nDeprecated: use Start.",
+				Doc: &DocConfig{Godoc: true}},
+			want: "// Run starts the service.\n//\n// Deprecated: use Start.\nfunc Run() {}\n",
+		},
+		{
+			name: "deprecated option appends paragraph",
+			req:  EditRequest{Symbol: "Config.Name", Content: "Name is the name.", Doc: &DocConfig{Deprecated: "use Label."}},
+			want: "\t// Name is the name.\n\t//\n\t// Deprecated: use Label.\n\tName string\n",
+		},
+		{
+			name: "replace doc keeps directives",
+			req:  EditRequest{Symbol: "data", Content: "data holds the contents of a.txt."},
+			want: "// data holds the contents of a.txt.\n//\n//go:embed a.txt\nvar data string",
+		},
+		{
+			name:    "remove doc keeps directives",
+			req:     EditRequest{Symbol: "Foo", Content: ""},
+			want:    "//go:noinline\nfunc Foo() {}",
+			wantNot: "not inlined",
+		},
+		{
+			name: "fuzzy match",
+			req:  EditRequest{Symbol: "config", Content: "Config holds settings.", FuzzyMatch: true},
+			want: "// Config holds settings.\ntype Config struct {",
+		},
+		{
+			name:     "missing symbol",
+			req:      EditRequest{Symbol: "Missing", Content: "Missing is missing."},
+			wantCode: CodeSymbolNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Path = "test.go"
+			tt.req.EditType = "set-doc"
+			result := EditSource(tt.req, []byte(initial))
+			if result.Code != tt.wantCode {
+				t.Fatalf("EditSource() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if !strings.Contains(result.Content, tt.want) {
+				t.Errorf("EditSource() content missing %q:\n%s", tt.want, result.Content)
+			}
+			if tt.wantNot != "" && strings.Contains(result.Content, tt.wantNot) {
+				t.Errorf("EditSource() content still contains %q:\n%s", tt.wantNot, result.Content)
+			}
+		})
+	}
+}
@@ -2,12 +2,16 @@ package parser
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/format"
 	"go/parser"
 	"go/printer"
 	"go/token"
+	"io/fs"
 	"os"
+	"strings"
 )
 
 // parseFile parses a Go source file and returns the AST
@@ -20,116 +24,242 @@ func validateRequest(req EditRequest) error {
 	if req.EditType == "" {
 		return fmt.Errorf("EditType is required")
 	}
-	if req.EditType != "replace" && req.EditType != "insert" && req.EditType != "delete" {
-		return fmt.Errorf("Invalid EditType: must be 'replace', 'insert', or 'delete'")
+	switch req.EditType {
+	case "replace", "insert", "delete", "replace-body", "set-doc", "insert-statement", "replace-statement", "delete-statement":
+	default:
+		return fmt.Errorf("Invalid EditType: must be 'replace', 'insert', 'delete', 'replace-body', 'set-doc', 'insert-statement', 'replace-statement' or 'delete-statement'")
 	}
-	if req.Symbol == "" {
+	// Inserts at a file position have no symbol to anchor on
+	if req.Symbol == "" && !(req.EditType == "insert" && req.Insert != nil && IsFilePosition(req.Insert.Position)) {
 		return fmt.Errorf("Symbol is required")
 	}
-	if req.EditType != "delete" && req.Content == "" {
+	if req.EditType != "delete" && req.EditType != "set-doc" && req.EditType != "delete-statement" && req.Content == "" {
 		return fmt.Errorf("Content is required for %s operations", req.EditType)
 	}
+	if strings.HasSuffix(req.EditType, "-statement") {
+		if req.Statement == nil || (req.Statement.Anchor == "") == (req.Statement.Path == "") {
+			return fmt.Errorf("Statement configuration with exactly one of Anchor or Path is required for %s operations", req.EditType)
+		}
+		if req.EditType == "insert-statement" && req.Statement.Position != "before" && req.Statement.Position != "after" {
+			return fmt.Errorf("Invalid Position in Statement config: must be 'before' or 'after'")
+		}
+	}
 	if req.EditType == "insert" {
 		if req.Insert == nil {
 			return fmt.Errorf("Insert configuration is required for insert operations")
 		}
-		if req.Insert.Position != "before" && req.Insert.Position != "after" {
-			return fmt.Errorf("Invalid Position in Insert config: must be 'before' or 'after'")
-		}
-		if req.Insert.RelativeToSymbol == "" {
-			return fmt.Errorf("RelativeToSymbol is required in Insert config")
+		if !IsFilePosition(req.Insert.Position) {
+			if req.Insert.Position != "before" && req.Insert.Position != "after" {
+				return fmt.Errorf("Invalid Position in Insert config: must be 'before', 'after', 'start', 'after-imports', 'end', 'first-method-of:T' or 'last-method-of:T'")
+			}
+			if req.Insert.RelativeToSymbol == "" {
+				return fmt.Errorf("RelativeToSymbol is required in Insert config")
+			}
 		}
 	}
 	return nil
 }
 
-// findParentGenDecl finds the parent GenDecl for a given TypeSpec
-func findParentGenDecl(file *ast.File, target ast.Node) *ast.GenDecl {
-	var parent *ast.GenDecl
-	ast.Inspect(file, func(n ast.Node) bool {
-		if n == nil {
-			return true
-		}
-		if genDecl, ok := n.(*ast.GenDecl); ok {
-			for _, spec := range genDecl.Specs {
-				if spec == target {
-					parent = genDecl
-					return false
+// generatedFileError describes why an edit to a generated file was refused
+func generatedFileError(generator string) *Error {
+	if generator == "" {
+		return newError(CodeGeneratedFile, nil, "Refusing to edit generated file: changes would be lost on regeneration (set AllowGenerated to override)")
+	}
+	return newError(CodeGeneratedFile, &ErrorDetails{Generator: generator},
+		"Refusing to edit file generated by %s: changes would be lost on regeneration (set AllowGenerated to override)", generator)
+}
+
+// findSymbol looks for a top-level declaration by name, or a method by
+// Type.Method, and returns it. A bare name matching several declarations
+// resolves to the only one that is not a method; otherwise the symbol is
+// reported as ambiguous together with its candidates.
+func findSymbol(fset *token.FileSet, file *ast.File, symbolName string) (ast.Decl, *Error) {
+	recv, name, qualified := strings.Cut(symbolName, ".")
+	if !qualified {
+		recv, name = "", symbolName
+	}
+
+	var matches []ast.Decl
+	var candidates []Candidate
+	nonMethods := 0
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.Name != name {
+				continue
+			}
+			candidate := Candidate{Name: d.Name.Name, Kind: "function"}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				r := receiverType(d.Recv.List[0].Type)
+				if qualified && r != recv {
+					continue
+				}
+				candidate.Name = r + "." + d.Name.Name
+				candidate.Kind = "method"
+			} else if qualified {
+				continue
+			} else {
+				nonMethods++
+			}
+			matches = append(matches, d)
+			candidates = append(candidates, declCandidate(fset, candidate, d))
+		case *ast.GenDecl:
+			if qualified || d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				if spec.(*ast.TypeSpec).Name.Name == name {
+					matches = append(matches, d)
+					candidates = append(candidates, declCandidate(fset, Candidate{Name: name, Kind: "type"}, spec))
+					nonMethods++
 				}
 			}
 		}
-		return true
-	})
-	return parent
-}
+	}
 
-// findSymbol looks for a symbol in the AST and returns its declaration
-func findSymbol(file *ast.File, symbolName string) (ast.Decl, bool) {
-	var targetDecl ast.Decl
-	var found bool
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) == 0:
+		return nil, notFoundError(fset, file, symbolName, kindDecls)
+	case nonMethods == 1:
+		for i, c := range candidates {
+			if c.Kind != "method" {
+				return matches[i], nil
+			}
+		}
+	}
+	return nil, newError(CodeAmbiguousSymbol, &ErrorDetails{Symbol: symbolName, Candidates: candidates},
+		"Symbol %s is ambiguous: %d declarations match (use Type.Method to select a method)", symbolName, len(matches))
+}
 
-	ast.Inspect(file, func(n ast.Node) bool {
-		if found {
-			return false
+// valueDecl returns the ungrouped var or const declaration of a single name,
+// or nil if there is none
+func valueDecl(file *ast.File, name string) *ast.GenDecl {
+	for _, decl := range file.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Lparen.IsValid() || (d.Tok != token.VAR && d.Tok != token.CONST) {
+			continue
 		}
-		if n == nil {
-			return true
+		if s := d.Specs[0].(*ast.ValueSpec); len(s.Names) == 1 && s.Names[0].Name == name {
+			return d
 		}
+	}
+	return nil
+}
 
-		switch v := n.(type) {
-		case *ast.FuncDecl:
-			if v.Name.Name == symbolName {
-				targetDecl = v
-				found = true
-				return false
-			}
-		case *ast.TypeSpec:
-			if v.Name.Name == symbolName {
-				if genDecl := findParentGenDecl(file, v); genDecl != nil {
-					targetDecl = genDecl
-					found = true
-					return false
-				}
+// findTarget finds the declaration an edit applies to. With fuzzy, a
+// misspelled symbol resolves to its only close match, which is returned as
+// resolved.
+func findTarget(fset *token.FileSet, file *ast.File, symbol string, fuzzy bool) (decl ast.Decl, resolved string, err *Error) {
+	decl, err = findSymbol(fset, file, symbol)
+	if err != nil && err.Code == CodeSymbolNotFound && fuzzy {
+		if name, ok := fuzzyResolve(fset, file, symbol, kindDecls); ok {
+			if match, matchErr := findSymbol(fset, file, name); matchErr == nil {
+				logger.Info("resolved misspelled symbol", "symbol", symbol, "resolved", name)
+				return match, name, nil
 			}
 		}
-		return true
-	})
+	}
+	return decl, "", err
+}
 
-	return targetDecl, found
+// declCandidate fills in the position of a candidate declaration
+func declCandidate(fset *token.FileSet, c Candidate, node ast.Node) Candidate {
+	pos := fset.Position(node.Pos())
+	c.Line = pos.Line
+	c.Start = pos.Offset
+	c.End = fset.Position(node.End()).Offset
+	return c
 }
 
-// Edit performs the requested code edit operation
+// Edit performs the requested code edit operation and writes the result
+// back to the file
 func Edit(req EditRequest) EditResult {
+	// Validate request before touching the file
+	if err := validateRequest(req); err != nil {
+		return failure(newError(CodeInvalidRequest, nil, "%v", err))
+	}
+
+	// Read the original file
+	content, err := os.ReadFile(req.Path)
+	if err != nil {
+		code := CodeReadFailed
+		if errors.Is(err, fs.ErrNotExist) {
+			code = CodeFileNotFound
+		}
+		return failure(newError(code, nil, "Failed to read file: %v", err))
+	}
+
+	result := EditSource(req, content)
+	if !result.Success {
+		return result
+	}
+
+	// Refuse to overwrite changes made by someone else since the file was read
+	current, err := os.ReadFile(req.Path)
+	if err != nil || !bytes.Equal(current, content) {
+		logger.Warn("file changed while editing", "path", req.Path)
+		return failure(newError(CodeFileChanged, nil, "File changed while editing: %s", req.Path))
+	}
+
+	// Write the result back to the file
+	if err := os.WriteFile(req.Path, []byte(result.Content), 0644); err != nil {
+		return failure(newError(CodeWriteFailed, nil, "Failed to write file: %v", err))
+	}
+	logger.Debug("wrote file", "path", req.Path, "bytes", len(result.Content))
+
+	return result
+}
+
+// EditSource performs the requested code edit operation on the given source
+// and returns the edited content without touching the file system
+func EditSource(req EditRequest, content []byte) EditResult {
+	log := logger.With("path", req.Path, "editType", req.EditType, "symbol", req.Symbol)
 	if req.EditType == "insert" && req.Insert != nil {
-		fmt.Printf("DEBUG: Insert config - Position: %s, RelativeToSymbol: %s\n",
-			req.Insert.Position, req.Insert.RelativeToSymbol)
+		log = log.With("position", req.Insert.Position, "relativeTo", req.Insert.RelativeToSymbol)
 	}
+	log.Debug("editing source")
 
 	// Validate request
 	if err := validateRequest(req); err != nil {
-		return EditResult{
-			Success: false,
-			Error:   err.Error(),
-		}
+		return failure(newError(CodeInvalidRequest, nil, "%v", err))
 	}
 
 	// Create a new token.FileSet for this operation
 	fset := token.NewFileSet()
 
-	// Read and parse the original file
-	content, err := os.ReadFile(req.Path)
+	// Parse the original source
+	file, err := parseFile(fset, req.Path, content)
 	if err != nil {
-		return EditResult{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to read file: %v", err),
-		}
+		return failure(newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse file: %v", err))
 	}
 
-	file, err := parseFile(fset, req.Path, content)
-	if err != nil {
-		return EditResult{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to parse file: %v", err),
+	// Generated files are overwritten by the next go generate run
+	if generator, generated := generatedBy(file); generated && !req.AllowGenerated {
+		return failure(generatedFileError(generator))
+	}
+
+	switch req.EditType {
+	case "replace-body":
+		return editBody(fset, file, content, req)
+	case "set-doc":
+		return editDoc(fset, file, content, req)
+	case "insert-statement", "replace-statement", "delete-statement":
+		return editStatement(fset, file, content, req)
+	}
+
+	if req.EditType == "insert" && IsFilePosition(req.Insert.Position) {
+		return insertAtPosition(fset, file, content, req)
+	}
+
+	// Struct fields and interface methods are spliced into their type rather
+	// than edited as declarations
+	targetSymbol := relativeTarget(req)
+	if strings.Contains(targetSymbol, ".") {
+		if sel, ok := memberTarget(fset, file, targetSymbol, req.FuzzyMatch); ok {
+			log.Debug("editing member", "member", sel.name)
+			return editMember(fset, file, content, req, sel)
 		}
 	}
 
@@ -141,17 +271,12 @@ func Edit(req EditRequest) EditResult {
 		newContent := fmt.Sprintf("package %s\n%s", file.Name.Name, req.Content)
 		newFile, err := parseFile(fset, "", newContent)
 		if err != nil {
-			return EditResult{
-				Success: false,
-				Error:   fmt.Sprintf("Failed to parse new content: %v", err),
-			}
+			// Report positions relative to the content, not the package clause added above
+			return failure(newError(CodeContentSyntax, syntaxDetails(err, 1), "Failed to parse new content: %v", err))
 		}
 
 		if len(newFile.Decls) == 0 {
-			return EditResult{
-				Success: false,
-				Error:   "No declaration found in new content",
-			}
+			return failure(newError(CodeContentSyntax, nil, "No declaration found in new content"))
 		}
 		newDecl = newFile.Decls[0]
 
@@ -162,17 +287,24 @@ func Edit(req EditRequest) EditResult {
 	}
 
 	// Find the target symbol
-	targetSymbol := req.Symbol
-	if req.EditType == "insert" {
-		targetSymbol = req.Insert.RelativeToSymbol
+	targetDecl, resolved, findErr := findTarget(fset, file, targetSymbol, req.FuzzyMatch)
+	if findErr != nil && findErr.Code == CodeSymbolNotFound && req.EditType == "delete" {
+		// A var or const declared on its own goes with its keyword
+		if decl := valueDecl(file, targetSymbol); decl != nil {
+			return deleteDecl(fset, content, decl, "")
+		}
 	}
-
-	targetDecl, found := findSymbol(file, targetSymbol)
-	if !found {
-		return EditResult{
-			Success: false,
-			Error:   fmt.Sprintf("Symbol not found: %s", targetSymbol),
+	if findErr != nil {
+		log.Debug("symbol lookup failed", "code", findErr.Code, "target", targetSymbol)
+		if findErr.Code == CodeSymbolNotFound && strings.Contains(targetSymbol, ".") {
+			// Members were tried above, so suggest them too
+			findErr = notFoundError(fset, file, targetSymbol, kindDecls|kindMembers)
 		}
+		return failure(findErr)
+	}
+
+	if req.EditType == "delete" {
+		return deleteDecl(fset, content, targetDecl, resolved)
 	}
 
 	// Create new declarations list
@@ -194,7 +326,7 @@ func Edit(req EditRequest) EditResult {
 				newDecls = append(newDecls, newDecl)
 			case "insert":
 				if req.Insert.Position == "before" {
-					fmt.Printf("DEBUG: Inserting before target\n")
+					log.Debug("inserting before target")
 					if newComment != nil {
 						switch d := newDecl.(type) {
 						case *ast.FuncDecl:
@@ -206,7 +338,7 @@ func Edit(req EditRequest) EditResult {
 					newDecls = append(newDecls, newDecl)
 					newDecls = append(newDecls, decl)
 				} else {
-					fmt.Printf("DEBUG: Inserting after target\n")
+					log.Debug("inserting after target")
 					newDecls = append(newDecls, decl)
 					if newComment != nil {
 						switch d := newDecl.(type) {
@@ -218,9 +350,6 @@ func Edit(req EditRequest) EditResult {
 					}
 					newDecls = append(newDecls, newDecl)
 				}
-			case "delete":
-				fmt.Printf("DEBUG: Skipping declaration (delete)\n")
-				continue
 			}
 		} else {
 			newDecls = append(newDecls, decl)
@@ -242,24 +371,62 @@ func Edit(req EditRequest) EditResult {
 		Tabwidth: 8,
 	}
 	if err := cfg.Fprint(&buf, fset, resultFile); err != nil {
-		return EditResult{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to format modified code: %v", err),
-		}
+		return failure(newError(CodeFormatFailed, nil, "Failed to format modified code: %v", err))
 	}
 
-	resultStr := buf.String()
+	return EditResult{
+		Success:        true,
+		Content:        buf.String(),
+		ResolvedSymbol: resolved,
+	}
+}
 
-	// Write the result back to the file
-	if err := os.WriteFile(req.Path, []byte(resultStr), 0644); err != nil {
-		return EditResult{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to write file: %v", err),
+// deleteDecl cuts a top-level declaration and its doc comment out of the
+// source text, so that comments elsewhere in the file are kept
+func deleteDecl(fset *token.FileSet, content []byte, decl ast.Decl, resolved string) EditResult {
+	start := decl.Pos()
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	}
+	from := fset.Position(start).Offset
+	to := fset.Position(decl.End()).Offset
+
+	// Take whole lines, with a trailing comment and one of the blank lines
+	// around them, unless the declaration shares its lines with other code
+	lineStart := from
+	for lineStart > 0 && (content[lineStart-1] == ' ' || content[lineStart-1] == '\t') {
+		lineStart--
+	}
+	lineEnd := bytes.IndexByte(content[to:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(content)
+	} else {
+		lineEnd += to
+	}
+	rest := strings.TrimSpace(string(content[to:lineEnd]))
+	if (lineStart == 0 || content[lineStart-1] == '\n') && (rest == "" || strings.HasPrefix(rest, "//")) {
+		from, to = lineStart, min(lineEnd+1, len(content))
+		if to < len(content) && content[to] == '\n' {
+			to++
+		} else if from >= 2 && content[from-2] == '\n' {
+			from--
 		}
 	}
 
+	formatted, err := format.Source(append(content[:from:from], content[to:]...))
+	if err != nil {
+		return failure(newError(CodeFormatFailed, nil, "Failed to format modified code: %v", err))
+	}
 	return EditResult{
-		Success: true,
-		Content: resultStr,
+		Success:        true,
+		Content:        string(formatted),
+		ResolvedSymbol: resolved,
 	}
 }
@@ -374,6 +374,115 @@ func Valid() {}`,
 				}
 			},
 		},
+		{
+			name: "delete keeps comments of other declarations",
+			initial: `package test
+
+func Keep() {
+	// explain the step
+	step() // inline
+}
+
+// Drop is going away
+func Drop() {}
+
+// More helpers follow
+`,
+			req: EditRequest{
+				Symbol:   "Drop",
+				EditType: "delete",
+			},
+			want: EditResult{
+				Success: true,
+			},
+			validate: func(t *testing.T, path string) {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := "package test\n\nfunc Keep() {\n\t// explain the step\n\tstep() // inline\n}\n\n// More helpers follow\n"
+				if string(content) != want {
+					t.Errorf("got:\n%s\nwant:\n%s", content, want)
+				}
+			},
+		},
+		{
+			name: "delete ungrouped variable",
+			initial: `package test
+
+var limit = 3
+
+const (
+	minWait = 1
+)
+`,
+			req: EditRequest{
+				Symbol:   "limit",
+				EditType: "delete",
+			},
+			want: EditResult{
+				Success: true,
+			},
+			validate: func(t *testing.T, path string) {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := "package test\n\nconst (\n\tminWait = 1\n)\n"; string(content) != want {
+					t.Errorf("got:\n%s\nwant:\n%s", content, want)
+				}
+			},
+		},
+		{
+			name: "refuse generated file",
+			initial: `// Code generated by protoc-gen-go. DO NOT EDIT.
+
+package test
+
+func Generated() {}`,
+			req: EditRequest{
+				Symbol:   "Generated",
+				EditType: "delete",
+			},
+			want: EditResult{
+				Success: false,
+				Error:   "Refusing to edit file generated by protoc-gen-go",
+			},
+			validate: func(t *testing.T, path string) {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(content), "func Generated()") {
+					t.Error("Generated file was modified")
+				}
+			},
+		},
+		{
+			name: "edit generated file with override",
+			initial: `// Code generated by protoc-gen-go. DO NOT EDIT.
+
+package test
+
+func Generated() {}`,
+			req: EditRequest{
+				Symbol:         "Generated",
+				EditType:       "delete",
+				AllowGenerated: true,
+			},
+			want: EditResult{
+				Success: true,
+			},
+			validate: func(t *testing.T, path string) {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if strings.Contains(string(content), "func Generated()") {
+					t.Error("Generated function was not deleted")
+				}
+			},
+		},
 		{
 			name: "handle missing insert config",
 			initial: `package test
@@ -403,7 +512,7 @@ func Existing() {}`,
 			},
 			want: EditResult{
 				Success: false,
-				Error:   "Invalid Position in Insert config: must be 'before' or 'after'",
+				Error:   "Invalid Position in Insert config: must be 'before', 'after', 'start', 'after-imports', 'end', 'first-method-of:T' or 'last-method-of:T'",
 			},
 		},
 		{
@@ -461,3 +570,255 @@ func Existing() {}`,
 		})
 	}
 }
+
+func TestEditErrorCodes(t *testing.T) {
+	initial := `package test
+
+type Service struct{}
+
+type Worker struct{ Name string }
+
+func (s *Service) Process() error {
+	return nil
+}
+
+func (w *Worker) Process() error {
+	return nil
+}
+
+func Run() {}
+
+var Retry = 3
+`
+
+	tests := []struct {
+		name     string
+		req      EditRequest
+		wantCode ErrorCode
+		validate func(t *testing.T, result EditResult)
+	}{
+		{
+			name:     "missing symbol",
+			req:      EditRequest{Symbol: "Missing", EditType: "delete"},
+			wantCode: CodeSymbolNotFound,
+			validate: func(t *testing.T, result EditResult) {
+				if result.Details == nil || result.Details.Symbol != "Missing" {
+					t.Errorf("Details = %+v, want symbol Missing", result.Details)
+				}
+			},
+		},
+		{
+			name:     "ambiguous method",
+			req:      EditRequest{Symbol: "Process", EditType: "delete"},
+			wantCode: CodeAmbiguousSymbol,
+			validate: func(t *testing.T, result EditResult) {
+				if result.Details == nil || len(result.Details.Candidates) != 2 {
+					t.Fatalf("Details = %+v, want two candidates", result.Details)
+				}
+				names := result.Details.Candidates[0].Name + "," + result.Details.Candidates[1].Name
+				if names != "Service.Process,Worker.Process" {
+					t.Errorf("Candidates = %s", names)
+				}
+				if result.Details.Candidates[0].Line != 7 {
+					t.Errorf("Candidate line = %d, want 7", result.Details.Candidates[0].Line)
+				}
+			},
+		},
+		{
+			name: "qualified method",
+			req:  EditRequest{Symbol: "Worker.Process", EditType: "delete"},
+			validate: func(t *testing.T, result EditResult) {
+				if strings.Contains(result.Content, "(w *Worker)") || !strings.Contains(result.Content, "(s *Service)") {
+					t.Errorf("Wrong method deleted:\n%s", result.Content)
+				}
+			},
+		},
+		{
+			name:     "content syntax",
+			req:      EditRequest{Symbol: "Run", EditType: "replace", Content: "func Run() {\n\treturn +\n}"},
+			wantCode: CodeContentSyntax,
+			validate: func(t *testing.T, result EditResult) {
+				if result.Details == nil || result.Details.Line != 3 {
+					t.Errorf("Details = %+v, want line 3 of the content", result.Details)
+				}
+			},
+		},
+		{
+			name:     "suggestions for misspelled symbol",
+			req:      EditRequest{Symbol: "run", EditType: "delete"},
+			wantCode: CodeSymbolNotFound,
+			validate: func(t *testing.T, result EditResult) {
+				if result.Details == nil || len(result.Details.Candidates) == 0 || result.Details.Candidates[0].Name != "Run" {
+					t.Fatalf("Details = %+v, want Run as the first candidate", result.Details)
+				}
+				if !strings.Contains(result.Error, "did you mean Run?") {
+					t.Errorf("Error = %q, want a suggestion", result.Error)
+				}
+			},
+		},
+		{
+			name:     "no suggestions the operation cannot edit",
+			req:      EditRequest{Symbol: "Retri", EditType: "delete"},
+			wantCode: CodeSymbolNotFound,
+			validate: func(t *testing.T, result EditResult) {
+				if result.Details == nil || len(result.Details.Candidates) != 0 {
+					t.Errorf("Details = %+v, want no candidates", result.Details)
+				}
+			},
+		},
+		{
+			name:     "suggestions for misspelled field",
+			req:      EditRequest{Symbol: "Worker.Nme", EditType: "delete"},
+			wantCode: CodeSymbolNotFound,
+			validate: func(t *testing.T, result EditResult) {
+				if result.Details == nil || len(result.Details.Candidates) == 0 || result.Details.Candidates[0].Name != "Worker.Name" {
+					t.Errorf("Details = %+v, want Worker.Name as the first candidate", result.Details)
+				}
+			},
+		},
+		{
+			name: "fuzzy match by case",
+			req:  EditRequest{Symbol: "run", EditType: "delete", FuzzyMatch: true},
+			validate: func(t *testing.T, result EditResult) {
+				if result.ResolvedSymbol != "Run" || strings.Contains(result.Content, "func Run()") {
+					t.Errorf("ResolvedSymbol = %q, content:\n%s", result.ResolvedSymbol, result.Content)
+				}
+			},
+		},
+		{
+			name: "fuzzy match by typo",
+			req:  EditRequest{Symbol: "Servce.Process", EditType: "delete", FuzzyMatch: true},
+			validate: func(t *testing.T, result EditResult) {
+				if result.ResolvedSymbol != "Service.Process" || strings.Contains(result.Content, "(s *Service)") {
+					t.Errorf("ResolvedSymbol = %q, content:\n%s", result.ResolvedSymbol, result.Content)
+				}
+			},
+		},
+		{
+			name:     "fuzzy match with several close candidates",
+			req:      EditRequest{Symbol: "Proces", EditType: "delete", FuzzyMatch: true},
+			wantCode: CodeSymbolNotFound,
+			validate: func(t *testing.T, result EditResult) {
+				if result.Details == nil || len(result.Details.Candidates) != 2 {
+					t.Errorf("Details = %+v, want both Process methods", result.Details)
+				}
+			},
+		},
+		{
+			name:     "invalid request",
+			req:      EditRequest{Symbol: "Run", EditType: "rename"},
+			wantCode: CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.go")
+			if err := os.WriteFile(path, []byte(initial), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tt.req.Path = path
+
+			result := Edit(tt.req)
+			if result.Code != tt.wantCode {
+				t.Errorf("Edit() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if result.Success != (tt.wantCode == "") {
+				t.Errorf("Edit() success = %v, want %v", result.Success, tt.wantCode == "")
+			}
+			if tt.validate != nil {
+				tt.validate(t, result)
+			}
+		})
+	}
+
+	// Missing files are distinguished from other read failures
+	result := Edit(EditRequest{Path: filepath.Join(t.TempDir(), "missing.go"), Symbol: "Run", EditType: "delete"})
+	if result.Code != CodeFileNotFound {
+		t.Errorf("Edit() on missing file code = %q, want %q", result.Code, CodeFileNotFound)
+	}
+}
+
+func TestEditReplaceBody(t *testing.T) {
+	initial := `package test
+
+// Process handles data.
+//
+// It is kept exactly as written.
+func (s *Service) Process(ctx context.Context, data []byte) (n int, err error) {
+	// old implementation
+	return 0, nil
+}
+
+func Declared() int
+
+var Value = 1
+`
+
+	tests := []struct {
+		name     string
+		req      EditRequest
+		wantCode ErrorCode
+		want     string
+	}{
+		{
+			name: "replace method body",
+			req: EditRequest{Symbol: "Service.Process", Content: `	if len(data) == 0 {
+		return 0, nil // nothing to do
+	}
+	return len(data), nil`},
+			want: `// Process handles data.
+//
+// It is kept exactly as written.
+func (s *Service) Process(ctx context.Context, data []byte) (n int, err error) {
+	if len(data) == 0 {
+		return 0, nil // nothing to do
+	}
+	return len(data), nil
+}
+`,
+		},
+		{
+			name: "unindented statements are formatted",
+			req:  EditRequest{Symbol: "Process", Content: "return 1, nil"},
+			want: "(n int, err error) {\n\treturn 1, nil\n}\n",
+		},
+		{
+			name:     "content closing the function",
+			req:      EditRequest{Symbol: "Process", Content: "return 0, nil\n}\n\nfunc Extra() {"},
+			wantCode: CodeContentSyntax,
+		},
+		{
+			name:     "content syntax",
+			req:      EditRequest{Symbol: "Process", Content: "return +"},
+			wantCode: CodeContentSyntax,
+		},
+		{
+			name:     "function without body",
+			req:      EditRequest{Symbol: "Declared", Content: "return 1"},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "missing function",
+			req:      EditRequest{Symbol: "Missing", Content: "return"},
+			wantCode: CodeSymbolNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Path = "test.go"
+			tt.req.EditType = "replace-body"
+			result := EditSource(tt.req, []byte(initial))
+			if result.Code != tt.wantCode {
+				t.Fatalf("EditSource() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if !strings.Contains(result.Content, tt.want) {
+				t.Errorf("EditSource() content missing %q:\n%s", tt.want, result.Content)
+			}
+			if tt.wantCode == "" && !strings.Contains(result.Content, "func Declared() int\n\nvar Value = 1\n") {
+				t.Errorf("Other declarations changed:\n%s", result.Content)
+			}
+		})
+	}
+}
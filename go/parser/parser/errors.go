@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+)
+
+// ErrorCode identifies the kind of failure so that callers can decide how to
+// recover without matching on error messages
+type ErrorCode string
+
+const (
+	CodeInvalidRequest     ErrorCode = "INVALID_REQUEST"     // The request is missing fields or has invalid values
+	CodeFileNotFound       ErrorCode = "FILE_NOT_FOUND"      // The target file does not exist
+	CodeReadFailed         ErrorCode = "READ_FAILED"         // The target file could not be read
+	CodeParseFailed        ErrorCode = "PARSE_FAILED"        // The target file is not valid Go
+	CodeSymbolNotFound     ErrorCode = "SYMBOL_NOT_FOUND"    // No declaration matches the symbol
+	CodeAmbiguousSymbol    ErrorCode = "AMBIGUOUS_SYMBOL"    // Several declarations match the symbol
+	CodeContentSyntax      ErrorCode = "CONTENT_SYNTAX"      // The new content is not a valid declaration
+	CodeGeneratedFile      ErrorCode = "GENERATED_FILE"      // The file is generated and AllowGenerated is unset
+	CodeFormatFailed       ErrorCode = "FORMAT_FAILED"       // The edited file could not be printed
+	CodeFileChanged        ErrorCode = "FILE_CHANGED"        // The file changed on disk while the edit was in progress
+	CodeWriteFailed        ErrorCode = "WRITE_FAILED"        // The edited file could not be written
+	CodeNameConflict       ErrorCode = "NAME_CONFLICT"       // A rename would collide with an existing name
+	CodeStatementNotFound  ErrorCode = "STATEMENT_NOT_FOUND" // No statement in the function matches the anchor
+	CodeAmbiguousStatement ErrorCode = "AMBIGUOUS_STATEMENT" // Several statements match the anchor
+	CodeFileExists         ErrorCode = "FILE_EXISTS"         // The file to create already exists and Overwrite is unset
+	CodeInternal           ErrorCode = "INTERNAL"            // Any other failure
+)
+
+// Candidate describes a declaration that a symbol could refer to, or a
+// statement an anchor could refer to, named by its path
+type Candidate struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Line  int    `json:"line"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// ErrorDetails carries structured information about a failure
+type ErrorDetails struct {
+	Symbol     string      `json:"symbol,omitempty"`     // The symbol that was looked up
+	Candidates []Candidate `json:"candidates,omitempty"` // Declarations the symbol may refer to
+	Line       int         `json:"line,omitempty"`       // Line of a syntax error, relative to the parsed source
+	Column     int         `json:"column,omitempty"`     // Column of a syntax error
+	Generator  string      `json:"generator,omitempty"`  // Generator named in a generated file header
+}
+
+// Error is an error with a stable code and structured details
+type Error struct {
+	Code    ErrorCode     `json:"code"`
+	Message string        `json:"message"`
+	Details *ErrorDetails `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// newError creates an Error with a formatted message
+func newError(code ErrorCode, details *ErrorDetails, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Details: details}
+}
+
+// ErrorFromResult returns the structured error of a failed edit, or nil
+func ErrorFromResult(result EditResult) *Error {
+	if result.Success {
+		return nil
+	}
+	code := result.Code
+	if code == "" {
+		code = CodeInternal
+	}
+	return &Error{Code: code, Message: result.Error, Details: result.Details}
+}
+
+// failure converts an error into a failed EditResult
+func failure(err *Error) EditResult {
+	return EditResult{
+		Success: false,
+		Error:   err.Message,
+		Code:    err.Code,
+		Details: err.Details,
+	}
+}
+
+// syntaxDetails extracts the position of the first syntax error, shifting
+// the line by lineOffset to account for any source prepended before parsing
+func syntaxDetails(err error, lineOffset int) *ErrorDetails {
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		return &ErrorDetails{
+			Line:   list[0].Pos.Line - lineOffset,
+			Column: list[0].Pos.Column,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions limits the candidates reported for a missing symbol
+const maxSuggestions = 5
+
+// symbolKinds is a set of the kinds of declarations an operation can resolve
+// a symbol to
+type symbolKinds uint8
+
+const (
+	kindFuncs   symbolKinds = 1 << iota // Functions and methods
+	kindTypes                           // Type declarations
+	kindValues                          // Variables and constants
+	kindMembers                         // Struct fields and interface methods
+
+	kindDecls = kindFuncs | kindTypes                // What findSymbol resolves
+	kindAll   = kindDecls | kindValues | kindMembers // What selectSymbol resolves
+)
+
+// suggestion is a declaration resembling a missing symbol
+type suggestion struct {
+	candidate Candidate
+	distance  int // Edit distance between the lowercased names
+}
+
+// fileCandidates lists the declarations, methods, struct fields and interface
+// methods of a file that are among kinds, with members qualified as
+// Type.Member
+func fileCandidates(fset *token.FileSet, file *ast.File, kinds symbolKinds) []Candidate {
+	var candidates []Candidate
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			c := Candidate{Name: d.Name.Name, Kind: "function"}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				c.Name = receiverType(d.Recv.List[0].Type) + "." + d.Name.Name
+				c.Kind = "method"
+			}
+			if kinds&kindFuncs != 0 {
+				candidates = append(candidates, declCandidate(fset, c, d))
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if kinds&kindTypes != 0 {
+						candidates = append(candidates, declCandidate(fset, Candidate{Name: s.Name.Name, Kind: typeKind(s)}, s))
+					}
+					var fields *ast.FieldList
+					kind := "field"
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						fields = t.Fields
+					case *ast.InterfaceType:
+						fields, kind = t.Methods, "method"
+					default:
+						continue
+					}
+					if kinds&kindMembers == 0 {
+						continue
+					}
+					for _, field := range fields.List {
+						for _, name := range field.Names {
+							candidates = append(candidates, declCandidate(fset, Candidate{Name: s.Name.Name + "." + name.Name, Kind: kind}, field))
+						}
+						if len(field.Names) == 0 {
+							if name := embeddedName(field.Type); name != "" {
+								candidates = append(candidates, declCandidate(fset, Candidate{Name: s.Name.Name + "." + name, Kind: kind}, field))
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					if kinds&kindValues == 0 {
+						continue
+					}
+					kind := "variable"
+					if d.Tok == token.CONST {
+						kind = "constant"
+					}
+					for _, name := range s.Names {
+						candidates = append(candidates, declCandidate(fset, Candidate{Name: name.Name, Kind: kind}, s))
+					}
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// suggestions ranks the declarations of a file among kinds by their
+// similarity to symbol. Bare names are also compared with the member part of
+// methods and fields, qualified names only with other qualified names.
+func suggestions(fset *token.FileSet, file *ast.File, symbol string, kinds symbolKinds) []suggestion {
+	query := strings.ToLower(symbol)
+	_, _, qualified := strings.Cut(symbol, ".")
+	threshold := len(symbol) / 3
+	if threshold < 2 {
+		threshold = 2
+	}
+
+	var found []suggestion
+	for _, c := range fileCandidates(fset, file, kinds) {
+		name := strings.ToLower(c.Name)
+		_, member, isMember := strings.Cut(name, ".")
+		if qualified && !isMember {
+			continue
+		}
+		distance := levenshtein(query, name)
+		if !qualified && isMember {
+			distance = levenshtein(query, member)
+			name = member
+		}
+		// Substrings only count once they are long enough to be meaningful
+		substring := len(query) >= 3 && strings.Contains(name, query) || len(name) >= 3 && strings.Contains(query, name)
+		if distance <= threshold || substring {
+			found = append(found, suggestion{candidate: c, distance: distance})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].distance < found[j].distance })
+	if len(found) > maxSuggestions {
+		found = found[:maxSuggestions]
+	}
+	return found
+}
+
+// notFoundError reports a missing symbol together with the declarations
+// among kinds it most resembles
+func notFoundError(fset *token.FileSet, file *ast.File, symbol string, kinds symbolKinds) *Error {
+	details := &ErrorDetails{Symbol: symbol}
+	for _, s := range suggestions(fset, file, symbol, kinds) {
+		details.Candidates = append(details.Candidates, s.candidate)
+	}
+	if len(details.Candidates) == 0 {
+		return newError(CodeSymbolNotFound, details, "Symbol not found: %s", symbol)
+	}
+	return newError(CodeSymbolNotFound, details, "Symbol not found: %s (did you mean %s?)", symbol, details.Candidates[0].Name)
+}
+
+// fuzzyResolve returns the only declaration among kinds that differs from
+// symbol by case, or by a single edit for names of four or more characters,
+// if there is exactly one
+func fuzzyResolve(fset *token.FileSet, file *ast.File, symbol string, kinds symbolKinds) (string, bool) {
+	maxDistance := 1
+	if len(symbol) < 4 {
+		maxDistance = 0
+	}
+	var match string
+	for _, s := range suggestions(fset, file, symbol, kinds) {
+		if s.distance > maxDistance {
+			break
+		}
+		if match != "" {
+			return "", false
+		}
+		match = s.candidate.Name
+	}
+	return match, match != ""
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,257 @@
+package parser
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// GetRequest represents a request for the source of one symbol
+type GetRequest struct {
+	Path          string // File declaring the symbol
+	Symbol        string // Name, Type.Method, or Type.Member for struct fields and interface methods
+	SignatureOnly bool   `json:",omitempty"` // Elide the function body and return just the signature
+}
+
+// GetResult contains the source of a symbol as it appears in the file
+type GetResult struct {
+	Success       bool          // Whether the symbol was found
+	Error         string        // Error message if unsuccessful
+	Code          ErrorCode     `json:",omitempty"` // Stable error code if unsuccessful
+	Details       *ErrorDetails `json:",omitempty"` // Structured error details, such as candidate symbols
+	Symbol        string        // Qualified name of the symbol that was found
+	Kind          string        // Kind of the symbol, as reported by Parse
+	EnclosingType string        `json:",omitempty"` // Receiver of a method, or the type declaring a field or interface method
+	Source        string        // Source text, including the doc comment
+	Start         int           // Byte offset of Source in the file
+	End           int           // Byte offset of the end of Source in the file
+	StartLine     int           // 1-based line of Start
+	EndLine       int           // 1-based line of End
+}
+
+// selection is a symbol resolved to its node in the syntax tree
+type selection struct {
+	name      string            // Qualified name
+	kind      string            // Kind as reported by Parse
+	enclosing string            // Receiver or declaring type of a member
+	node      ast.Node          // FuncDecl, GenDecl with a single spec, grouped spec, or Field
+	doc       *ast.CommentGroup // Doc comment of node
+	comment   *ast.CommentGroup // Trailing line comment of node
+}
+
+// Get returns the source of a symbol, including its doc comment
+func Get(req GetRequest) GetResult {
+	if req.Path == "" || req.Symbol == "" {
+		return getFailure(newError(CodeInvalidRequest, nil, "Path and Symbol are required"))
+	}
+	content, err := os.ReadFile(req.Path)
+	if err != nil {
+		code := CodeReadFailed
+		if errors.Is(err, fs.ErrNotExist) {
+			code = CodeFileNotFound
+		}
+		return getFailure(newError(code, nil, "Failed to read file: %v", err))
+	}
+	return GetSource(req, content)
+}
+
+// GetSource returns the source of a symbol from the given file content
+func GetSource(req GetRequest, content []byte) GetResult {
+	fset := token.NewFileSet()
+	file, err := parseFile(fset, req.Path, content)
+	if err != nil {
+		return getFailure(newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse file: %v", err))
+	}
+
+	sel, selErr := selectSymbol(fset, file, req.Symbol)
+	if selErr != nil {
+		return getFailure(selErr)
+	}
+
+	start, end := sel.node.Pos(), sel.node.End()
+	if sel.doc != nil {
+		start = sel.doc.Pos()
+	}
+	if sel.comment != nil && sel.comment.End() > end {
+		end = sel.comment.End()
+	}
+	if fn, ok := sel.node.(*ast.FuncDecl); ok && req.SignatureOnly && fn.Body != nil {
+		end = fn.Body.Lbrace
+	}
+
+	startOffset := fset.Position(start).Offset
+	endOffset := fset.Position(end).Offset
+	source := string(content[startOffset:endOffset])
+	if req.SignatureOnly {
+		source = strings.TrimRight(source, " \t")
+		endOffset = startOffset + len(source)
+	}
+
+	return GetResult{
+		Success:       true,
+		Symbol:        sel.name,
+		Kind:          sel.kind,
+		EnclosingType: sel.enclosing,
+		Source:        source,
+		Start:         startOffset,
+		End:           endOffset,
+		StartLine:     fset.Position(start).Line,
+		EndLine:       fset.Position(start).Line + strings.Count(source, "\n"),
+	}
+}
+
+// getFailure converts an error into a failed GetResult
+func getFailure(err *Error) GetResult {
+	return GetResult{
+		Success: false,
+		Error:   err.Message,
+		Code:    err.Code,
+		Details: err.Details,
+	}
+}
+
+// selectSymbol resolves a symbol to its declaration. Bare names select
+// functions, types, variables and constants, falling back to methods as
+// findSymbol does; Type.Member selects a method, then a struct field or
+// interface method of Type.
+func selectSymbol(fset *token.FileSet, file *ast.File, symbol string) (selection, *Error) {
+	typeName, member, qualified := strings.Cut(symbol, ".")
+
+	decl, findErr := findSymbol(fset, file, symbol)
+	if fn, isFunc := decl.(*ast.FuncDecl); !qualified && (findErr != nil || (isFunc && fn.Recv != nil)) {
+		// A variable or constant wins over methods of the same name, as a
+		// function or type does in findSymbol
+		if sel, ok := valueSelection(file, symbol); ok {
+			return sel, nil
+		}
+	}
+	if findErr != nil && findErr.Code != CodeSymbolNotFound {
+		return selection{}, findErr
+	}
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		sel := selection{name: d.Name.Name, kind: "function", node: d, doc: d.Doc}
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			sel.enclosing = receiverType(d.Recv.List[0].Type)
+			sel.name = sel.enclosing + "." + d.Name.Name
+			sel.kind = "method"
+		}
+		return sel, nil
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			if s := spec.(*ast.TypeSpec); s.Name.Name == symbol {
+				return specSelection(d, s, s.Name.Name, typeKind(s), s.Doc, s.Comment), nil
+			}
+		}
+	}
+
+	if qualified {
+		for _, decl := range file.Decls {
+			d, ok := decl.(*ast.GenDecl)
+			if !ok || d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				if s := spec.(*ast.TypeSpec); s.Name.Name == typeName {
+					if sel, ok := memberSelection(s, member); ok {
+						return sel, nil
+					}
+				}
+			}
+		}
+	}
+	return selection{}, notFoundError(fset, file, symbol, kindAll)
+}
+
+// valueSelection selects the variable or constant declared with a name
+func valueSelection(file *ast.File, symbol string) (selection, bool) {
+	for _, decl := range file.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || (d.Tok != token.VAR && d.Tok != token.CONST) {
+			continue
+		}
+		for _, spec := range d.Specs {
+			s := spec.(*ast.ValueSpec)
+			for _, name := range s.Names {
+				if name.Name == symbol {
+					kind := "variable"
+					if d.Tok == token.CONST {
+						kind = "constant"
+					}
+					return specSelection(d, s, symbol, kind, s.Doc, s.Comment), true
+				}
+			}
+		}
+	}
+	return selection{}, false
+}
+
+// specSelection selects a spec, or its whole declaration when the spec is
+// not part of a group so that the keyword and doc comment are included
+func specSelection(d *ast.GenDecl, spec ast.Spec, name, kind string, doc, comment *ast.CommentGroup) selection {
+	if d.Lparen == token.NoPos {
+		return selection{name: name, kind: kind, node: d, doc: d.Doc, comment: comment}
+	}
+	return selection{name: name, kind: kind, node: spec, doc: doc, comment: comment}
+}
+
+// memberSelection selects a struct field or interface method of a type
+func memberSelection(s *ast.TypeSpec, member string) (selection, bool) {
+	var fields *ast.FieldList
+	kind := "field"
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields, kind = t.Methods, "method"
+	default:
+		return selection{}, false
+	}
+	for _, field := range fields.List {
+		names := field.Names
+		if len(names) == 0 {
+			// Embedded fields are named after their type
+			if name := embeddedName(field.Type); name != "" {
+				names = []*ast.Ident{{Name: name}}
+			}
+		}
+		for _, name := range names {
+			if name.Name == member {
+				return selection{
+					name:      s.Name.Name + "." + member,
+					kind:      kind,
+					enclosing: s.Name.Name,
+					node:      field,
+					doc:       field.Doc,
+					comment:   field.Comment,
+				}, true
+			}
+		}
+	}
+	return selection{}, false
+}
+
+// embeddedName returns the field name of an embedded type
+func embeddedName(expr ast.Expr) string {
+	if sel, ok := expr.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name
+	}
+	if star, ok := expr.(*ast.StarExpr); ok {
+		return embeddedName(star.X)
+	}
+	return receiverType(expr)
+}
+
+// typeKind returns the kind Parse reports for a type declaration
+func typeKind(s *ast.TypeSpec) string {
+	switch s.Type.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	}
+	return "type"
+}
@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	content := `package test
+
+import "io"
+
+// Service processes requests
+type Service struct {
+	// Name identifies the service
+	Name string ` + "`json:\"name\"`" + ` // trailing
+	io.Reader
+}
+
+// Process handles data
+func (s *Service) Process() error {
+	return nil
+}
+
+type (
+	// ID is an identifier
+	ID int
+	Other string
+)
+
+// Limit caps the batch size
+const Limit = 10
+
+var (
+	a, b = 1, 2
+)
+
+type Store interface {
+	// Load returns a value
+	Load(key string) (string, error)
+}
+
+// Limit returns the batch size of the service
+func (s *Service) Limit() int { return Limit }
+`
+	path := filepath.Join(t.TempDir(), "test.go")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		req           GetRequest
+		wantCode      ErrorCode
+		wantSymbol    string
+		wantKind      string
+		wantEnclosing string
+		wantSource    string
+		wantLines     [2]int
+		wantCandidate string // First suggestion for a missing symbol
+	}{
+		{
+			name:       "type with doc",
+			req:        GetRequest{Symbol: "Service"},
+			wantSymbol: "Service",
+			wantKind:   "struct",
+			wantSource: "// Service processes requests\ntype Service struct {\n\t// Name identifies the service\n\tName string `json:\"name\"` // trailing\n\tio.Reader\n}",
+			wantLines:  [2]int{5, 10},
+		},
+		{
+			name:          "method",
+			req:           GetRequest{Symbol: "Service.Process"},
+			wantSymbol:    "Service.Process",
+			wantKind:      "method",
+			wantEnclosing: "Service",
+			wantSource:    "// Process handles data\nfunc (s *Service) Process() error {\n\treturn nil\n}",
+			wantLines:     [2]int{12, 15},
+		},
+		{
+			name:          "method by bare name",
+			req:           GetRequest{Symbol: "Process", SignatureOnly: true},
+			wantSymbol:    "Service.Process",
+			wantKind:      "method",
+			wantEnclosing: "Service",
+			wantSource:    "// Process handles data\nfunc (s *Service) Process() error",
+			wantLines:     [2]int{12, 13},
+		},
+		{
+			name:          "field with tag and comments",
+			req:           GetRequest{Symbol: "Service.Name"},
+			wantSymbol:    "Service.Name",
+			wantKind:      "field",
+			wantEnclosing: "Service",
+			wantSource:    "// Name identifies the service\n\tName string `json:\"name\"` // trailing",
+			wantLines:     [2]int{7, 8},
+		},
+		{
+			name:          "embedded field",
+			req:           GetRequest{Symbol: "Service.Reader"},
+			wantSymbol:    "Service.Reader",
+			wantKind:      "field",
+			wantEnclosing: "Service",
+			wantSource:    "io.Reader",
+			wantLines:     [2]int{9, 9},
+		},
+		{
+			name:       "grouped type",
+			req:        GetRequest{Symbol: "ID"},
+			wantSymbol: "ID",
+			wantKind:   "type",
+			wantSource: "// ID is an identifier\n\tID int",
+			wantLines:  [2]int{18, 19},
+		},
+		{
+			// Service.Limit does not take the bare name from the constant
+			name:       "constant",
+			req:        GetRequest{Symbol: "Limit"},
+			wantSymbol: "Limit",
+			wantKind:   "constant",
+			wantSource: "// Limit caps the batch size\nconst Limit = 10",
+			wantLines:  [2]int{23, 24},
+		},
+		{
+			name:          "method named like a constant",
+			req:           GetRequest{Symbol: "Service.Limit"},
+			wantSymbol:    "Service.Limit",
+			wantKind:      "method",
+			wantEnclosing: "Service",
+			wantSource:    "// Limit returns the batch size of the service\nfunc (s *Service) Limit() int { return Limit }",
+			wantLines:     [2]int{35, 36},
+		},
+		{
+			name:       "variable in multi-name spec",
+			req:        GetRequest{Symbol: "b"},
+			wantSymbol: "b",
+			wantKind:   "variable",
+			wantSource: "a, b = 1, 2",
+			wantLines:  [2]int{27, 27},
+		},
+		{
+			name:          "interface method",
+			req:           GetRequest{Symbol: "Store.Load", SignatureOnly: true},
+			wantSymbol:    "Store.Load",
+			wantKind:      "method",
+			wantEnclosing: "Store",
+			wantSource:    "// Load returns a value\n\tLoad(key string) (string, error)",
+			wantLines:     [2]int{31, 32},
+		},
+		{
+			name:     "missing member",
+			req:      GetRequest{Symbol: "Service.Missing"},
+			wantCode: CodeSymbolNotFound,
+		},
+		{
+			name:     "missing symbol",
+			req:      GetRequest{Symbol: "Missing"},
+			wantCode: CodeSymbolNotFound,
+		},
+		{
+			name:          "miscased field",
+			req:           GetRequest{Symbol: "Service.name"},
+			wantCode:      CodeSymbolNotFound,
+			wantCandidate: "Service.Name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Path = path
+			result := Get(tt.req)
+			if result.Code != tt.wantCode {
+				t.Fatalf("Get() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if tt.wantCode != "" {
+				if result.Details == nil || result.Details.Symbol != tt.req.Symbol {
+					t.Fatalf("Get() details = %+v", result.Details)
+				}
+				if tt.wantCandidate != "" && (len(result.Details.Candidates) == 0 || result.Details.Candidates[0].Name != tt.wantCandidate) {
+					t.Errorf("Get() candidates = %+v, want %s first", result.Details.Candidates, tt.wantCandidate)
+				}
+				return
+			}
+			if result.Symbol != tt.wantSymbol || result.Kind != tt.wantKind || result.EnclosingType != tt.wantEnclosing {
+				t.Errorf("Get() = %s %s in %q, want %s %s in %q", result.Kind, result.Symbol, result.EnclosingType, tt.wantKind, tt.wantSymbol, tt.wantEnclosing)
+			}
+			if result.Source != tt.wantSource {
+				t.Errorf("Get() source = %q, want %q", result.Source, tt.wantSource)
+			}
+			if content[result.Start:result.End] != result.Source {
+				t.Errorf("Get() range %d-%d does not match source", result.Start, result.End)
+			}
+			if [2]int{result.StartLine, result.EndLine} != tt.wantLines {
+				t.Errorf("Get() lines = %d-%d, want %d-%d", result.StartLine, result.EndLine, tt.wantLines[0], tt.wantLines[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// brokenImplementations returns the types of the package containing path
+// that implement the interface named iface with the file as before, but not
+// with it as after
+func brokenImplementations(path, iface string, before, after []byte) ([]string, *Error) {
+	fset := token.NewFileSet()
+	// Sharing the importer type-checks each dependency once
+	imp := importer.ForCompiler(fset, "source", nil)
+
+	was, err := implementers(fset, imp, path, before, iface)
+	if err != nil {
+		return nil, err
+	}
+	now, err := implementers(fset, imp, path, after, iface)
+	if err != nil {
+		return nil, err
+	}
+	var broken []string
+	for name := range was {
+		if !now[name] {
+			broken = append(broken, name)
+		}
+	}
+	sort.Strings(broken)
+	return broken, nil
+}
+
+// implementers type-checks the package containing path, with content in place
+// of the file, and returns the named types whose value or pointer implements
+// iface. Type errors elsewhere in the package are tolerated.
+func implementers(fset *token.FileSet, imp types.Importer, path string, content []byte, iface string) (map[string]bool, *Error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	candidates, err := packageFiles(dir, PackageOptions{})
+	if err != nil {
+		return nil, newError(CodeReadFailed, nil, "Failed to read package directory: %v", err)
+	}
+
+	target, err := parseFile(fset, path, content)
+	if err != nil {
+		return nil, newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse file: %v", err)
+	}
+	files := []*ast.File{target}
+	for _, candidate := range candidates {
+		if candidate.name == base {
+			continue
+		}
+		p := filepath.Join(dir, candidate.name)
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return nil, newError(CodeReadFailed, nil, "Failed to read file: %v", err)
+		}
+		f, err := parseFile(fset, p, src)
+		if err != nil {
+			return nil, newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse file: %v", err)
+		}
+		if f.Name.Name == target.Name.Name {
+			files = append(files, f)
+		}
+	}
+
+	conf := types.Config{Importer: imp, Error: func(error) {}}
+	pkg, _ := conf.Check(target.Name.Name, fset, files, nil)
+
+	found := make(map[string]bool)
+	obj, ok := pkg.Scope().Lookup(iface).(*types.TypeName)
+	if !ok {
+		return found, nil
+	}
+	it, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return found, nil
+	}
+	for _, name := range pkg.Scope().Names() {
+		tn, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok || tn == obj || types.IsInterface(tn.Type()) {
+			continue
+		}
+		// Generic types only implement interfaces once instantiated
+		if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			continue
+		}
+		if types.Implements(tn.Type(), it) || types.Implements(types.NewPointer(tn.Type()), it) {
+			found[name] = true
+		}
+	}
+	return found, nil
+}
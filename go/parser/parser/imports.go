@@ -0,0 +1,334 @@
+package parser
+
+import (
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// importContext describes the package a file belongs to, for resolving the
+// packages its code refers to
+type importContext struct {
+	dir      string            // Directory of the package
+	specs    map[string]string // Import specs by package name, such as `"fmt"`, from the other files of the package
+	declared map[string]bool   // Names declared at package level by the other files
+	only     map[string]bool   // When set, the only names whose imports may be removed; none are added
+}
+
+var (
+	stdOnce sync.Once
+	stdPkgs map[string][]string // Standard library import paths by package name
+
+	namesMu sync.Mutex
+	names   = make(map[string]string) // Package names of non-standard import paths
+)
+
+// stdPackages indexes the importable packages of the standard library by
+// name, reading GOROOT once
+func stdPackages() map[string][]string {
+	stdOnce.Do(func() {
+		stdPkgs = make(map[string][]string)
+		root := filepath.Join(build.Default.GOROOT, "src")
+		filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			name := d.Name()
+			if rel == "cmd" || name == "internal" || name == "vendor" || name == "testdata" {
+				return filepath.SkipDir
+			}
+			if rel == "." {
+				return nil
+			}
+			matches, _ := filepath.Glob(filepath.Join(path, "*.go"))
+			for _, m := range matches {
+				if !strings.HasSuffix(m, "_test.go") {
+					path := filepath.ToSlash(rel)
+					stdPkgs[lastElement(path)] = append(stdPkgs[lastElement(path)], path)
+					break
+				}
+			}
+			return nil
+		})
+	})
+	return stdPkgs
+}
+
+// isStdPath reports whether an import path belongs to the standard library
+func isStdPath(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// importName returns the name an import spec binds in the file. Packages
+// outside the standard library are looked up from dir, falling back to the
+// conventional name derived from the path.
+func importName(spec *ast.ImportSpec, dir string) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	path, _ := strconv.Unquote(spec.Path.Value)
+	if isStdPath(path) {
+		return lastElement(path)
+	}
+
+	namesMu.Lock()
+	defer namesMu.Unlock()
+	if name, ok := names[path]; ok {
+		return name
+	}
+	name := ""
+	if pkg, err := build.Default.Import(path, dir, 0); err == nil {
+		name = pkg.Name
+	} else {
+		// Drop the go- or .vN decorations
+		name = strings.TrimPrefix(lastElement(path), "go-")
+		if i := strings.Index(name, ".v"); i > 0 {
+			name = name[:i]
+		}
+		name = strings.ReplaceAll(name, "-", "")
+	}
+	names[path] = name
+	return name
+}
+
+// lastElement returns the last element of an import path, skipping a major
+// version suffix such as the v2 of math/rand/v2
+func lastElement(path string) string {
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	return name
+}
+
+// packageImportContext reads the files of dir other than skip to learn the
+// imports and package-level names of the package called pkg
+func packageImportContext(dir, pkg, skip string) (importContext, *Error) {
+	ctx := importContext{dir: dir, specs: make(map[string]string), declared: make(map[string]bool)}
+	candidates, err := packageFiles(dir, PackageOptions{IncludeTests: true})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ctx, nil
+		}
+		return ctx, newError(CodeReadFailed, nil, "Failed to read package directory: %v", err)
+	}
+	fset := token.NewFileSet()
+	for _, candidate := range candidates {
+		if candidate.name == skip {
+			continue
+		}
+		f, err := parseFile(fset, filepath.Join(dir, candidate.name), nil)
+		if err != nil {
+			// A broken sibling only costs the hints it would have given
+			continue
+		}
+		ctx.addImports(f)
+		if f.Name.Name != pkg {
+			continue
+		}
+		for name := range f.Scope.Objects {
+			ctx.declared[name] = true
+		}
+	}
+	return ctx, nil
+}
+
+// addImports records the import specs of f, replacing those of other files
+// that bind the same names
+func (ctx importContext) addImports(f *ast.File) {
+	for _, spec := range f.Imports {
+		name := importName(spec, ctx.dir)
+		switch {
+		case name == "_" || name == ".":
+		case spec.Name != nil:
+			ctx.specs[name] = name + " " + spec.Path.Value
+		default:
+			ctx.specs[name] = spec.Path.Value
+		}
+	}
+}
+
+// usedPackages returns the names the declarations of file use as package
+// qualifiers, excluding names declared in the file or elsewhere in its package
+func usedPackages(file *ast.File, declared map[string]bool) map[string]bool {
+	used := make(map[string]bool)
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			continue
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil && !declared[x.Name] && file.Scope.Lookup(x.Name) == nil {
+				used[x.Name] = true
+			}
+			return true
+		})
+	}
+	return used
+}
+
+// fixImports removes the imports of src that its code does not use and adds
+// those it is missing, resolved from the other files of the package and then
+// from the standard library. It returns the formatted source and the package
+// names no import could be found for.
+func fixImports(src []byte, ctx importContext) ([]byte, []string, *Error) {
+	fset := token.NewFileSet()
+	file, err := parseFile(fset, "", src)
+	if err != nil {
+		return nil, nil, newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse file: %v", err)
+	}
+	used := usedPackages(file, ctx.declared)
+
+	type splice struct {
+		from, to int
+		text     string
+	}
+	var splices []splice
+	have := make(map[string]bool)
+	var keep []*ast.ImportSpec
+	var group *ast.GenDecl  // First grouped import declaration that stays
+	var single *ast.GenDecl // Last ungrouped import declaration that stays
+	for _, decl := range file.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.IMPORT {
+			continue
+		}
+		var unused []*ast.ImportSpec
+		for _, spec := range d.Specs {
+			s := spec.(*ast.ImportSpec)
+			name := importName(s, ctx.dir)
+			path, _ := strconv.Unquote(s.Path.Value)
+			if used[name] || name == "_" || name == "." || path == "C" || (ctx.only != nil && !ctx.only[name]) {
+				have[name] = true
+				keep = append(keep, s)
+			} else {
+				unused = append(unused, s)
+			}
+		}
+		if len(unused) < len(d.Specs) {
+			if !d.Lparen.IsValid() {
+				single = d
+			} else if group == nil {
+				group = d
+			}
+		}
+		switch {
+		case len(unused) == 0:
+		case len(unused) == len(d.Specs):
+			from := d.Pos()
+			if d.Doc != nil {
+				from = d.Doc.Pos()
+			}
+			splices = append(splices, splice{from: lineStart(fset, src, from), to: lineEnd(fset, file, src, d.End()) + 1})
+		default:
+			for _, s := range unused {
+				splices = append(splices, splice{from: lineStart(fset, src, s.Pos()), to: lineEnd(fset, file, src, s.End()) + 1})
+			}
+		}
+	}
+
+	var missing, unresolved []string
+	for name := range used {
+		if have[name] || ctx.only != nil {
+			continue
+		}
+		if spec, ok := ctx.specs[name]; ok {
+			missing = append(missing, spec)
+		} else if paths := stdPackages()[name]; len(paths) == 1 {
+			missing = append(missing, strconv.Quote(paths[0]))
+		} else {
+			unresolved = append(unresolved, name)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unresolved)
+
+	if len(missing) > 0 {
+		switch {
+		case group != nil:
+			// Standard packages join the standard group, others go last
+			var lastStd *ast.ImportSpec
+			for _, spec := range group.Specs {
+				if path, _ := strconv.Unquote(spec.(*ast.ImportSpec).Path.Value); isStdPath(path) {
+					lastStd = spec.(*ast.ImportSpec)
+				}
+			}
+			std, other := groupSpecs(missing)
+			stdAt := lineEnd(fset, file, src, group.Lparen) + 1
+			if lastStd != nil {
+				stdAt = lineEnd(fset, file, src, lastStd.End()) + 1
+			}
+			splices = append(splices,
+				splice{from: stdAt, to: stdAt, text: std},
+				splice{from: lineStart(fset, src, group.Rparen), to: lineStart(fset, src, group.Rparen), text: other})
+		case single != nil:
+			// Turn the last ungrouped import into a group holding the new ones
+			spec := single.Specs[0]
+			std, other := groupSpecs(append(missing, string(src[fset.Position(spec.Pos()).Offset:fset.Position(spec.End()).Offset])))
+			from, to := fset.Position(single.Pos()).Offset, fset.Position(single.End()).Offset
+			splices = append(splices, splice{from: from, to: to, text: importBlock(std, other)})
+		default:
+			std, other := groupSpecs(missing)
+			at := lineEnd(fset, file, src, file.Name.End()) + 1
+			splices = append(splices, splice{from: at, to: at, text: "\n" + importBlock(std, other) + "\n"})
+		}
+	}
+
+	// Apply from the end so that earlier offsets stay valid
+	sort.SliceStable(splices, func(i, j int) bool { return splices[i].from > splices[j].from })
+	out := string(src)
+	for _, s := range splices {
+		from, to := min(s.from, len(out)), min(s.to, len(out))
+		out = out[:from] + s.text + out[to:]
+	}
+	formatted, err := format.Source([]byte(out))
+	if err != nil {
+		return nil, nil, newError(CodeFormatFailed, nil, "Failed to format modified code: %v", err)
+	}
+	return formatted, unresolved, nil
+}
+
+// groupSpecs renders import specs as lines of an import block, split into
+// the standard library and other packages
+func groupSpecs(specs []string) (std, other string) {
+	for _, spec := range specs {
+		quoted := spec[strings.Index(spec, `"`):]
+		if path, _ := strconv.Unquote(quoted); isStdPath(path) {
+			std += "\t" + spec + "\n"
+		} else {
+			other += "\t" + spec + "\n"
+		}
+	}
+	return std, other
+}
+
+// importBlock renders a grouped import declaration, separating the
+// standard library from other packages
+func importBlock(std, other string) string {
+	if std != "" && other != "" {
+		std += "\n"
+	}
+	return "import (\n" + std + other + ")"
+}
+
+// lineStart returns the offset of the start of the line containing pos
+func lineStart(fset *token.FileSet, content []byte, pos token.Pos) int {
+	offset := fset.Position(pos).Offset
+	for offset > 0 && content[offset-1] != '\n' {
+		offset--
+	}
+	return offset
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFixImports(t *testing.T) {
+	tests := []struct {
+		name           string
+		src            string
+		want           string
+		wantUnresolved []string
+	}{
+		{
+			name: "grouped imports keep their groups",
+			src: `package p
+
+import (
+	"fmt"
+	"os"
+
+	"example.com/lib"
+)
+
+func F() { fmt.Println(lib.X, strings.ToUpper(""), other.Y) }
+`,
+			want: `package p
+
+import (
+	"fmt"
+	"strings"
+
+	"example.com/lib"
+)
+
+func F() { fmt.Println(lib.X, strings.ToUpper(""), other.Y) }
+`,
+			wantUnresolved: []string{"other"},
+		},
+		{
+			name: "single import dropped",
+			src:  "package p\n\n// Needed once.\nimport \"fmt\"\n\nfunc F() {}\n",
+			want: "package p\n\nfunc F() {}\n",
+		},
+		{
+			name: "ungrouped import becomes a group",
+			src:  "package p\n\nimport \"fmt\"\n\nfunc F() { fmt.Print(io.EOF) }\n",
+			want: "package p\n\nimport (\n\t\"fmt\"\n\t\"io\"\n)\n\nfunc F() { fmt.Print(io.EOF) }\n",
+		},
+		{
+			name: "versioned standard library import is kept",
+			src:  "package p\n\nimport \"math/rand/v2\"\n\nfunc F() int { return rand.IntN(6) }\n",
+			want: "package p\n\nimport \"math/rand/v2\"\n\nfunc F() int { return rand.IntN(6) }\n",
+		},
+		{
+			name: "blank, dot and local names are left alone",
+			src: `package p
+
+import (
+	_ "embed"
+	. "math"
+)
+
+type conf struct{ v int }
+
+func F(c conf) int { return c.v + int(Pi) + cfg.v }
+`,
+			want: `package p
+
+import (
+	_ "embed"
+	. "math"
+)
+
+type conf struct{ v int }
+
+func F(c conf) int { return c.v + int(Pi) + cfg.v }
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := importContext{dir: t.TempDir(), declared: map[string]bool{"cfg": true}}
+			got, unresolved, err := fixImports([]byte(tt.src), ctx)
+			if err != nil {
+				t.Fatalf("fixImports() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("fixImports() =\n%s\nwant:\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(unresolved, tt.wantUnresolved) {
+				t.Errorf("fixImports() unresolved = %v, want %v", unresolved, tt.wantUnresolved)
+			}
+		})
+	}
+}
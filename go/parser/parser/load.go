@@ -0,0 +1,336 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// typedPackage is a package type-checked from source together with the
+// files it was checked from
+type typedPackage struct {
+	dir     string
+	path    string // Import path
+	files   []*ast.File
+	names   []string // Paths of files, in the same order
+	content [][]byte // Contents of files, in the same order
+	pkg     *types.Package
+	info    *types.Info
+	errs    []error // Type errors, which do not stop the check
+}
+
+// loader type-checks packages from source, sharing one file set. Imports of
+// packages inside the module are resolved to the packages the loader has
+// checked itself, so that objects keep their identity across packages;
+// other imports are read from source by the standard importer.
+type loader struct {
+	fset    *token.FileSet
+	root    string            // Module root directory, or "" outside a module
+	module  string            // Module path
+	overlay map[string][]byte // Contents to use in place of files on disk, by absolute path
+	std     types.ImporterFrom
+	pkgs    map[string]*typedPackage // Packages without their tests, by import path
+	loading map[string]bool          // Import paths being checked, to detect cycles
+}
+
+// newLoader returns a loader for the module containing dir
+func newLoader(dir string, overlay map[string][]byte) *loader {
+	fset := token.NewFileSet()
+	l := &loader{
+		fset:    fset,
+		overlay: overlay,
+		std:     importer.ForCompiler(fset, "source", nil).(types.ImporterFrom),
+		pkgs:    make(map[string]*typedPackage),
+		loading: make(map[string]bool),
+	}
+	l.root, l.module = findModule(dir)
+	return l
+}
+
+// withOverlay returns a loader for the same module that reads overlay in
+// place of the files on disk, sharing the standard library packages l has
+// already imported
+func (l *loader) withOverlay(overlay map[string][]byte) *loader {
+	return &loader{
+		fset:    l.fset,
+		root:    l.root,
+		module:  l.module,
+		overlay: overlay,
+		std:     l.std,
+		pkgs:    make(map[string]*typedPackage),
+		loading: make(map[string]bool),
+	}
+}
+
+// findModule returns the root directory and path of the module containing
+// dir, or empty strings when there is none
+func findModule(dir string) (root, module string) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", ""
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		if data, err := os.ReadFile(filepath.Join(d, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) >= 2 && fields[0] == "module" {
+					path := fields[1]
+					if unquoted, err := strconv.Unquote(path); err == nil {
+						path = unquoted
+					}
+					return d, path
+				}
+			}
+			return "", ""
+		}
+		if filepath.Dir(d) == d {
+			return "", ""
+		}
+	}
+}
+
+// importPath returns the import path of the package in dir
+func (l *loader) importPath(dir string) string {
+	abs, _ := filepath.Abs(dir)
+	if l.root != "" {
+		if rel, err := filepath.Rel(l.root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			if rel == "." {
+				return l.module
+			}
+			return l.module + "/" + filepath.ToSlash(rel)
+		}
+	}
+	// Outside a module the directory stands in for the path
+	return filepath.ToSlash(abs)
+}
+
+// Import implements types.Importer
+func (l *loader) Import(path string) (*types.Package, error) {
+	return l.ImportFrom(path, "", 0)
+}
+
+// ImportFrom implements types.ImporterFrom
+func (l *loader) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if p, ok := l.pkgs[path]; ok {
+		return p.pkg, nil
+	}
+	if l.module == "" || (path != l.module && !strings.HasPrefix(path, l.module+"/")) {
+		return l.std.ImportFrom(path, dir, mode)
+	}
+	if l.loading[path] {
+		return nil, fmt.Errorf("import cycle through %s", path)
+	}
+	l.loading[path] = true
+	defer delete(l.loading, path)
+
+	pkgDir := filepath.Join(l.root, filepath.FromSlash(strings.TrimPrefix(strings.TrimPrefix(path, l.module), "/")))
+	pkgs, err := l.loadDir(pkgDir, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", pkgDir)
+	}
+	return pkgs[0].pkg, nil
+}
+
+// loadDir type-checks the package in dir. With tests its internal _test.go
+// files are checked with it and the external test package, if any, follows
+// it in the result, importing the package as checked with its tests.
+func (l *loader) loadDir(dir string, tests bool) ([]*typedPackage, *Error) {
+	path := l.importPath(dir)
+	if p, ok := l.pkgs[path]; ok && !tests {
+		return []*typedPackage{p}, nil
+	}
+
+	candidates, err := packageFiles(dir, PackageOptions{IncludeTests: tests})
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, newError(CodeFileNotFound, nil, "Package directory not found: %s", dir)
+		}
+		return nil, newError(CodeReadFailed, nil, "Failed to read package directory: %v", err)
+	}
+	candidates = append(candidates, l.overlayFiles(dir, candidates, tests)...)
+
+	primary := &typedPackage{dir: dir, path: path}
+	xtest := &typedPackage{dir: dir, path: path + "_test"}
+	var name string
+	var parsed []*ast.File
+	var names []string
+	var contents [][]byte
+	for _, candidate := range candidates {
+		p, _ := filepath.Abs(filepath.Join(dir, candidate.name))
+		src, ok := l.overlay[p]
+		if !ok {
+			if src, err = os.ReadFile(p); err != nil {
+				return nil, newError(CodeReadFailed, nil, "Failed to read file: %v", err)
+			}
+		}
+		f, err := parseFile(l.fset, p, src)
+		if err != nil {
+			return nil, newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse %s: %v", p, err)
+		}
+		if name == "" && !strings.HasSuffix(candidate.name, "_test.go") && f.Name.Name != "documentation" {
+			name = f.Name.Name
+		}
+		parsed, names, contents = append(parsed, f), append(names, p), append(contents, src)
+	}
+	if name == "" && len(parsed) > 0 {
+		// A directory of tests alone is named after its internal test package
+		name = strings.TrimSuffix(parsed[0].Name.Name, "_test")
+	}
+	for i, f := range parsed {
+		target := primary
+		if f.Name.Name != name {
+			if f.Name.Name != name+"_test" || !strings.HasSuffix(names[i], "_test.go") {
+				continue
+			}
+			target = xtest
+		}
+		target.files = append(target.files, f)
+		target.names = append(target.names, names[i])
+		target.content = append(target.content, contents[i])
+	}
+	if len(primary.files) == 0 && len(xtest.files) == 0 {
+		return nil, nil
+	}
+
+	l.check(primary, l)
+	if !tests {
+		l.pkgs[path] = primary
+		return []*typedPackage{primary}, nil
+	}
+	result := []*typedPackage{primary}
+	if len(xtest.files) > 0 {
+		// The external tests see the package together with its internal tests
+		l.check(xtest, importerFunc(func(p, dir string, mode types.ImportMode) (*types.Package, error) {
+			// Outside a module the package can only be recognized by its name
+			if p == path || (l.module == "" && p[strings.LastIndex(p, "/")+1:] == name) {
+				return primary.pkg, nil
+			}
+			return l.ImportFrom(p, dir, mode)
+		}))
+		result = append(result, xtest)
+	}
+	return result, nil
+}
+
+// overlayFiles returns the files of the overlay in dir that are not yet on
+// disk, such as one a move creates, and match the build configuration
+func (l *loader) overlayFiles(dir string, listed []packageFile, tests bool) []packageFile {
+	abs, _ := filepath.Abs(dir)
+	ctxt := build.Default
+	ctxt.OpenFile = func(path string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(l.overlay[path])), nil
+	}
+	var files []packageFile
+	for p := range l.overlay {
+		name := filepath.Base(p)
+		if filepath.Dir(p) != abs || !strings.HasSuffix(name, ".go") || (!tests && strings.HasSuffix(name, "_test.go")) {
+			continue
+		}
+		if slices.ContainsFunc(listed, func(f packageFile) bool { return f.name == name }) {
+			continue
+		}
+		if match, err := ctxt.MatchFile(abs, name); err == nil && match {
+			files = append(files, packageFile{name: name})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files
+}
+
+// addedErrors returns a type error of after that before does not have, once
+// rewrite is applied to the messages of before, or nil. When every message
+// is known but after has more errors, its first error is returned.
+func addedErrors(before, after []*typedPackage, rewrite func(string) string) error {
+	count := 0
+	known := make(map[string]bool)
+	for _, p := range before {
+		count += len(p.errs)
+		for _, err := range p.errs {
+			if typeErr, ok := err.(types.Error); ok {
+				known[rewrite(typeErr.Msg)] = true
+			}
+		}
+	}
+	var first error
+	for _, p := range after {
+		count -= len(p.errs)
+		for _, err := range p.errs {
+			if typeErr, ok := err.(types.Error); !ok || !known[typeErr.Msg] {
+				return err
+			}
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if count < 0 {
+		return first
+	}
+	return nil
+}
+
+// check type-checks the files of p, recording type errors instead of
+// stopping at the first
+func (l *loader) check(p *typedPackage, imp types.ImporterFrom) {
+	p.info = &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	conf := types.Config{
+		Importer: imp,
+		Error:    func(err error) { p.errs = append(p.errs, err) },
+	}
+	p.pkg, _ = conf.Check(p.path, l.fset, p.files, p.info)
+	if len(p.errs) > 0 {
+		logger.Debug("type errors in package", "path", p.path, "count", len(p.errs), "first", p.errs[0])
+	}
+}
+
+// file returns the index of the file at path in p, or -1
+func (p *typedPackage) file(path string) int {
+	for i, name := range p.names {
+		if name == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// packageOf returns the package among pkgs holding the file at path, and the
+// file itself
+func packageOf(pkgs []*typedPackage, path string) (*typedPackage, *ast.File, *Error) {
+	for _, p := range pkgs {
+		if i := p.file(path); i >= 0 {
+			return p, p.files[i], nil
+		}
+	}
+	return nil, nil, newError(CodeInvalidRequest, nil, "%s is not part of the package in its directory under the current build configuration", path)
+}
+
+// importerFunc adapts a function to types.ImporterFrom
+type importerFunc func(path, dir string, mode types.ImportMode) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path, "", 0) }
+
+func (f importerFunc) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	return f(path, dir, mode)
+}
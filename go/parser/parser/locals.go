@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// localSymbols collects the symbols declared inside a function body: local
+// type declarations, function literals bound to names and t.Run subtests.
+// Anonymous function literals are transparent, so anything declared inside
+// them is reported as belonging to the enclosing function. Depth limits how
+// many levels of named closures and subtests are descended into.
+func localSymbols(fset *token.FileSet, body *ast.BlockStmt, depth int) []Symbol {
+	if depth <= 0 || body == nil {
+		return nil
+	}
+
+	var symbols []Symbol
+
+	// closure builds a symbol for a function literal bound to a name
+	closure := func(name string, kind string, start, end token.Pos, lit *ast.FuncLit) Symbol {
+		return Symbol{
+			Name:     name,
+			Kind:     kind,
+			Start:    fset.Position(start).Offset,
+			End:      fset.Position(end).Offset,
+			Children: localSymbols(fset, lit.Body, depth-1),
+		}
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.DeclStmt:
+			d, ok := v.Decl.(*ast.GenDecl)
+			if !ok {
+				return true
+			}
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					symbols = append(symbols, typeSymbol(fset, d, s))
+				case *ast.ValueSpec:
+					// var handler = func(...) {...}
+					for i, value := range s.Values {
+						lit, ok := value.(*ast.FuncLit)
+						if !ok || i >= len(s.Names) || s.Names[i].Name == "_" {
+							continue
+						}
+						symbols = append(symbols, closure(s.Names[i].Name, "closure", s.Names[i].Pos(), lit.End(), lit))
+					}
+				}
+			}
+			return false
+
+		case *ast.AssignStmt:
+			// handler := func(...) {...}
+			if len(v.Lhs) != len(v.Rhs) {
+				return true
+			}
+			named := false
+			for i, rhs := range v.Rhs {
+				lit, ok := rhs.(*ast.FuncLit)
+				if !ok {
+					continue
+				}
+				ident, ok := v.Lhs[i].(*ast.Ident)
+				if !ok || ident.Name == "_" {
+					continue
+				}
+				symbols = append(symbols, closure(ident.Name, "closure", ident.Pos(), lit.End(), lit))
+				named = true
+			}
+			return !named
+
+		case *ast.CallExpr:
+			// t.Run("name", func(t *testing.T) {...})
+			name, lit, ok := subtest(v)
+			if !ok {
+				return true
+			}
+			symbols = append(symbols, closure(name, "subtest", v.Pos(), v.End(), lit))
+			return false
+		}
+		return true
+	})
+
+	return symbols
+}
+
+// subtest reports whether call is a t.Run or b.Run subtest invocation and
+// returns the subtest name and body
+func subtest(call *ast.CallExpr) (string, *ast.FuncLit, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Run" || len(call.Args) != 2 {
+		return "", nil, false
+	}
+	lit, ok := call.Args[1].(*ast.FuncLit)
+	if !ok || !isTestingParam(lit.Type) {
+		return "", nil, false
+	}
+	if bl, ok := call.Args[0].(*ast.BasicLit); ok && bl.Kind == token.STRING {
+		if name, err := strconv.Unquote(bl.Value); err == nil {
+			return name, lit, true
+		}
+	}
+	// Table-driven tests name their subtests with an expression such as tt.name
+	return types.ExprString(call.Args[0]), lit, true
+}
+
+// isTestingParam reports whether a function type takes a single *testing.T or *testing.B
+func isTestingParam(ft *ast.FuncType) bool {
+	if ft.Params == nil || len(ft.Params.List) != 1 {
+		return false
+	}
+	star, ok := ft.Params.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "testing" && (sel.Sel.Name == "T" || sel.Sel.Name == "B")
+}
@@ -0,0 +1,26 @@
+package parser
+
+import (
+	"context"
+	"log/slog"
+)
+
+// logger receives the package's diagnostics. It discards everything until
+// SetLogger is called, so that library use stays silent.
+var logger = slog.New(discardHandler{})
+
+// SetLogger routes the package's diagnostics to l
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(discardHandler{})
+	}
+	logger = l
+}
+
+// discardHandler is a slog.Handler that drops all records
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"strings"
+)
+
+// memberTarget resolves a Type.Member selector to a struct field or an
+// interface method. With fuzzy, a misspelled selector resolves to its only
+// close match.
+func memberTarget(fset *token.FileSet, file *ast.File, symbol string, fuzzy bool) (selection, bool) {
+	sel, err := selectSymbol(fset, file, symbol)
+	if err != nil && err.Code == CodeSymbolNotFound && fuzzy {
+		if name, ok := fuzzyResolve(fset, file, symbol, kindMembers); ok {
+			sel, err = selectSymbol(fset, file, name)
+		}
+	}
+	if err != nil {
+		return selection{}, false
+	}
+	_, isMember := sel.node.(*ast.Field)
+	return sel, isMember
+}
+
+// parseMembers checks that content is a valid list of members for the kind
+// of type that declares them
+func parseMembers(fset *token.FileSet, pkg, typeKind, content string) (*ast.FieldList, *Error) {
+	src := fmt.Sprintf("package %s\ntype _ %s {\n%s\n}", pkg, typeKind, content)
+	file, err := parseFile(fset, "", src)
+	if err != nil {
+		// Report positions relative to the content, not the wrapper added above
+		return nil, newError(CodeContentSyntax, syntaxDetails(err, 2), "Failed to parse new content: %v", err)
+	}
+	var fields *ast.FieldList
+	switch t := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields = t.Methods
+	}
+	if fields == nil || len(fields.List) == 0 {
+		return nil, newError(CodeContentSyntax, nil, "No %s member found in new content", typeKind)
+	}
+	return fields, nil
+}
+
+// editMember replaces, inserts next to or deletes a struct field or interface
+// method by splicing the source text, so that the other members keep their
+// tags and comments
+func editMember(fset *token.FileSet, file *ast.File, content []byte, req EditRequest, sel selection) EditResult {
+	field := sel.node.(*ast.Field)
+	typeKind := "struct"
+	if sel.kind == "method" {
+		typeKind = "interface"
+	}
+
+	newContent := strings.TrimSpace(req.Content)
+	if req.EditType != "delete" {
+		if _, err := parseMembers(token.NewFileSet(), file.Name.Name, typeKind, newContent); err != nil {
+			return failure(err)
+		}
+	}
+
+	// The member spans its doc comment and trailing line comment
+	start, end := field.Pos(), field.End()
+	if sel.doc != nil {
+		start = sel.doc.Pos()
+	}
+	if sel.comment != nil {
+		end = sel.comment.End()
+	}
+	startOffset := fset.Position(start).Offset
+	endOffset := fset.Position(end).Offset
+
+	// Work on whole lines unless the member shares its line with others
+	lineStart := startOffset
+	for lineStart > 0 && (content[lineStart-1] == ' ' || content[lineStart-1] == '\t') {
+		lineStart--
+	}
+	ownLine := lineStart == 0 || content[lineStart-1] == '\n'
+	lineEnd := endOffset
+	for lineEnd < len(content) && (content[lineEnd] == ' ' || content[lineEnd] == '\t') {
+		lineEnd++
+	}
+	ownLine = ownLine && lineEnd < len(content) && content[lineEnd] == '\n'
+
+	var edited string
+	switch req.EditType {
+	case "replace":
+		if len(field.Names) > 1 {
+			// Take the name out of the field, which keeps its other names, doc
+			// and comment, and declare the replacement on its own line above
+			// the field and its doc
+			from, to := nameRange(fset, field, sel.name[strings.LastIndex(sel.name, ".")+1:])
+			at := startOffset
+			if ownLine {
+				at = lineStart
+			}
+			edited = string(content[:at]) + newContent + "\n" + string(content[at:from]) + string(content[to:])
+		} else {
+			edited = string(content[:startOffset]) + newContent + string(content[endOffset:])
+		}
+	case "insert":
+		if req.Insert.Position == "before" {
+			at := startOffset
+			if ownLine {
+				at = lineStart
+			}
+			edited = string(content[:at]) + newContent + "\n" + string(content[at:])
+		} else {
+			edited = string(content[:endOffset]) + "\n" + newContent + string(content[endOffset:])
+		}
+	case "delete":
+		from, to := startOffset, endOffset
+		if len(field.Names) > 1 {
+			// Remove one name from a field declaring several
+			from, to = nameRange(fset, field, sel.name[strings.LastIndex(sel.name, ".")+1:])
+		} else if ownLine {
+			from, to = lineStart, lineEnd+1
+		}
+		edited = string(content[:from]) + string(content[to:])
+	}
+
+	formatted, err := format.Source([]byte(edited))
+	if err != nil {
+		return failure(newError(CodeFormatFailed, nil, "Failed to format modified code: %v", err))
+	}
+	result := EditResult{
+		Success: true,
+		Content: string(formatted),
+	}
+	if sel.name != relativeTarget(req) {
+		result.ResolvedSymbol = sel.name
+	}
+	if typeKind == "interface" && req.CheckImplementations {
+		broken, checkErr := brokenImplementations(req.Path, sel.enclosing, content, formatted)
+		if checkErr != nil {
+			return failure(checkErr)
+		}
+		result.BrokenImplementations = broken
+	}
+	return result
+}
+
+// nameRange returns the byte range covering one of the names of a field
+// together with the comma separating it from its neighbour
+func nameRange(fset *token.FileSet, field *ast.Field, name string) (int, int) {
+	for i, ident := range field.Names {
+		if ident.Name != name {
+			continue
+		}
+		if i+1 < len(field.Names) {
+			return fset.Position(ident.Pos()).Offset, fset.Position(field.Names[i+1].Pos()).Offset
+		}
+		return fset.Position(field.Names[i-1].End()).Offset, fset.Position(ident.End()).Offset
+	}
+	return 0, 0
+}
+
+// relativeTarget returns the symbol an edit request is anchored on
+func relativeTarget(req EditRequest) string {
+	if req.EditType == "insert" && req.Insert != nil {
+		return req.Insert.RelativeToSymbol
+	}
+	return req.Symbol
+}
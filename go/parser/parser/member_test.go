@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditStructFields(t *testing.T) {
+	initial := `package test
+
+// Config holds settings
+type Config struct {
+	// Name identifies the config
+	Name    string ` + "`json:\"name\"`" + ` // required
+	Timeout int    ` + "`json:\"timeout,omitempty\"`" + `
+	X, Y    int
+}
+
+type Point struct{ X int }
+
+type Rect struct {
+	// Corner of the rectangle
+	X, Y int // coords
+	W    int
+}
+
+// Process handles data
+func Process() {
+	// keep this comment
+}
+`
+
+	tests := []struct {
+		name     string
+		req      EditRequest
+		wantCode ErrorCode
+		want     []string
+		notWant  []string
+		resolved string
+	}{
+		{
+			name: "insert after field",
+			req: EditRequest{EditType: "insert", Symbol: "Retries", Content: "Retries int `json:\"retries\"` // attempts",
+				Insert: &InsertConfig{Position: "after", RelativeToSymbol: "Config.Timeout"}},
+			want: []string{
+				"\tName    string `json:\"name\"` // required\n",
+				"\tTimeout int    `json:\"timeout,omitempty\"`\n\tRetries int    `json:\"retries\"` // attempts\n\tX, Y    int\n",
+				"// keep this comment",
+			},
+		},
+		{
+			name: "insert before documented field",
+			req: EditRequest{EditType: "insert", Symbol: "ID", Content: "// ID is unique\nID int64",
+				Insert: &InsertConfig{Position: "before", RelativeToSymbol: "Config.Name"}},
+			want: []string{"struct {\n\t// ID is unique\n\tID int64\n\t// Name identifies the config\n\tName    string"},
+		},
+		{
+			name: "replace field",
+			req:  EditRequest{EditType: "replace", Symbol: "Config.Timeout", Content: "Timeout time.Duration `json:\"timeout\"`"},
+			want: []string{
+				"\tTimeout time.Duration `json:\"timeout\"`\n",
+				"// Name identifies the config\n\tName    string        `json:\"name\"` // required\n",
+			},
+			notWant: []string{"omitempty"},
+		},
+		{
+			name:    "delete field with doc and comment",
+			req:     EditRequest{EditType: "delete", Symbol: "Config.Name"},
+			want:    []string{"type Config struct {\n\tTimeout int `json:\"timeout,omitempty\"`\n\tX, Y    int\n}"},
+			notWant: []string{"Name", "required"},
+		},
+		{
+			name: "delete one of several names",
+			req:  EditRequest{EditType: "delete", Symbol: "Config.X"},
+			want: []string{"\tY       int\n"},
+		},
+		{
+			name: "replace one of several names",
+			req:  EditRequest{EditType: "replace", Symbol: "Rect.X", Content: "X float64"},
+			want: []string{"\tX float64\n\t// Corner of the rectangle\n\tY int // coords\n\tW int\n"},
+		},
+		{
+			name: "replace last of several documented names",
+			req:  EditRequest{EditType: "replace", Symbol: "Rect.Y", Content: "Y float64"},
+			want: []string{"\tY float64\n\t// Corner of the rectangle\n\tX int // coords\n\tW int\n"},
+		},
+		{
+			name: "single-line struct",
+			req: EditRequest{EditType: "insert", Symbol: "Y", Content: "Y int",
+				Insert: &InsertConfig{Position: "after", RelativeToSymbol: "Point.X"}},
+			want: []string{"type Point struct {\n\tX int\n\tY int\n}"},
+		},
+		{
+			name:     "fuzzy field",
+			req:      EditRequest{EditType: "delete", Symbol: "Config.timeout", FuzzyMatch: true},
+			notWant:  []string{"Timeout"},
+			resolved: "Config.Timeout",
+		},
+		{
+			name:     "invalid field content",
+			req:      EditRequest{EditType: "replace", Symbol: "Config.Timeout", Content: "Timeout int `unterminated"},
+			wantCode: CodeContentSyntax,
+		},
+		{
+			name:     "missing field",
+			req:      EditRequest{EditType: "delete", Symbol: "Config.Missing"},
+			wantCode: CodeSymbolNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.go")
+			if err := os.WriteFile(path, []byte(initial), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tt.req.Path = path
+
+			result := Edit(tt.req)
+			if result.Code != tt.wantCode {
+				t.Fatalf("Edit() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if result.ResolvedSymbol != tt.resolved {
+				t.Errorf("Edit() resolved = %q, want %q", result.ResolvedSymbol, tt.resolved)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("Edited file missing %q:\n%s", want, content)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(content), notWant) {
+					t.Errorf("Edited file still contains %q:\n%s", notWant, content)
+				}
+			}
+		})
+	}
+}
+
+func TestEditInterfaceMethods(t *testing.T) {
+	store := `package test
+
+// Store persists values
+type Store interface {
+	// Load returns a value
+	Load(key string) (string, error)
+	Save(key, value string) error // overwrites
+}
+`
+	impl := `package test
+
+type memory struct{}
+
+func (m *memory) Load(key string) (string, error) { return "", nil }
+func (m *memory) Save(key, value string) error    { return nil }
+
+type readOnly struct{}
+
+func (readOnly) Load(key string) (string, error) { return "", nil }
+`
+
+	tests := []struct {
+		name       string
+		req        EditRequest
+		wantCode   ErrorCode
+		want       []string
+		notWant    []string
+		wantBroken []string
+	}{
+		{
+			name: "insert method",
+			req: EditRequest{EditType: "insert", Symbol: "Delete", Content: "// Delete removes a value\nDelete(key string) error",
+				Insert: &InsertConfig{Position: "after", RelativeToSymbol: "Store.Save"}, CheckImplementations: true},
+			want:       []string{"\tSave(key, value string) error // overwrites\n\t// Delete removes a value\n\tDelete(key string) error\n}"},
+			wantBroken: []string{"memory"},
+		},
+		{
+			name:    "replace method",
+			req:     EditRequest{EditType: "replace", Symbol: "Store.Load", Content: "// Load returns a value or an error\nLoad(ctx any, key string) (string, error)"},
+			want:    []string{"// Load returns a value or an error\n\tLoad(ctx any, key string) (string, error)\n\tSave"},
+			notWant: []string{"// Load returns a value\n"},
+		},
+		{
+			name:    "delete method",
+			req:     EditRequest{EditType: "delete", Symbol: "Store.Save", CheckImplementations: true},
+			want:    []string{"type Store interface {\n\t// Load returns a value\n\tLoad(key string) (string, error)\n}"},
+			notWant: []string{"overwrites"},
+		},
+		{
+			name:     "struct field content for an interface",
+			req:      EditRequest{EditType: "replace", Symbol: "Store.Load", Content: "Load string `json:\"load\"`"},
+			wantCode: CodeContentSyntax,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writePackage(t, map[string]string{"store.go": store, "memory.go": impl})
+			tt.req.Path = filepath.Join(dir, "store.go")
+
+			result := Edit(tt.req)
+			if result.Code != tt.wantCode {
+				t.Fatalf("Edit() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if strings.Join(result.BrokenImplementations, ",") != strings.Join(tt.wantBroken, ",") {
+				t.Errorf("Edit() broken implementations = %v, want %v", result.BrokenImplementations, tt.wantBroken)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result.Content, want) {
+					t.Errorf("Edited file missing %q:\n%s", want, result.Content)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(result.Content, notWant) {
+					t.Errorf("Edited file still contains %q:\n%s", notWant, result.Content)
+				}
+			}
+		})
+	}
+}
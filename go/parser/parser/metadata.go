@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileInfo holds the file-level metadata of a Go source file
+type FileInfo struct {
+	Package         string   `json:"package"`
+	PackageDoc      string   `json:"packageDoc,omitempty"`
+	Imports         []Import `json:"imports,omitempty"`
+	BuildConstraint string   `json:"buildConstraint,omitempty"`
+	Generated       bool     `json:"generated,omitempty"`
+	Generator       string   `json:"generator,omitempty"`
+	GoGenerate      []string `json:"goGenerate,omitempty"`
+}
+
+// Import represents a single import spec
+type Import struct {
+	Path  string `json:"path"`
+	Name  string `json:"name,omitempty"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// generatedPattern matches the standard marker of generated files,
+// see https://go.dev/s/generatedcode
+var generatedPattern = regexp.MustCompile(`^// Code generated (.*)DO NOT EDIT\.$`)
+
+// fileInfo collects the metadata of a parsed file
+func fileInfo(fset *token.FileSet, path string, file *ast.File) *FileInfo {
+	info := &FileInfo{
+		Package:         file.Name.Name,
+		BuildConstraint: fileConstraint(filepath.Base(path), file),
+	}
+	if file.Doc != nil {
+		info.PackageDoc = cleanDoc(file.Doc.Text())
+	}
+
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			importPath = spec.Path.Value
+		}
+		imp := Import{
+			Path:  importPath,
+			Start: fset.Position(spec.Pos()).Offset,
+			End:   fset.Position(spec.End()).Offset,
+		}
+		if spec.Name != nil {
+			imp.Name = spec.Name.Name
+		}
+		info.Imports = append(info.Imports, imp)
+	}
+
+	info.Generator, info.Generated = generatedBy(file)
+
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if directive, ok := strings.CutPrefix(c.Text, "//go:generate "); ok {
+				info.GoGenerate = append(info.GoGenerate, strings.TrimSpace(directive))
+			}
+		}
+	}
+
+	return info
+}
+
+// generatedBy reports whether the file carries a "Code generated ... DO NOT
+// EDIT." marker before its package clause and returns the generator named
+// in it, if any
+func generatedBy(file *ast.File) (string, bool) {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			m := generatedPattern.FindStringSubmatch(c.Text)
+			if m == nil {
+				continue
+			}
+			generator := strings.TrimSpace(m[1])
+			generator = strings.TrimSuffix(generator, ".")
+			generator = strings.TrimPrefix(generator, "by ")
+			return strings.TrimSpace(generator), true
+		}
+	}
+	return "", false
+}
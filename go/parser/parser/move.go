@@ -0,0 +1,350 @@
+package parser
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MoveRequest represents a request to move a declaration to another file of
+// the same package
+type MoveRequest struct {
+	Path           string // File declaring the symbol
+	Symbol         string // Name, Type.Method, or a variable or type of a group
+	Destination    string // File to move the declaration to, created when missing
+	WithMethods    bool   `json:",omitempty"` // Move the methods the file declares on a moved type along with it
+	AllowGenerated bool   `json:",omitempty"` // Permit moving out of or into generated files
+	// Permit moving between files built under different constraints
+	AllowConstraintMismatch bool `json:",omitempty"`
+}
+
+// MoveResult contains the files changed by a move
+type MoveResult struct {
+	Success           bool          // Whether the move was performed
+	Error             string        // Error message if unsuccessful
+	Code              ErrorCode     `json:",omitempty"` // Stable error code if unsuccessful
+	Details           *ErrorDetails `json:",omitempty"` // Structured error details, such as candidate symbols
+	Moved             []string      // Qualified names of the moved declarations, in source order
+	Files             []FileChange  // The source and destination files as written
+	UnresolvedImports []string      `json:",omitempty"` // Package names no import was found for in the destination
+}
+
+// newFileHeader returns the start of a new destination file: the package
+// clause of the source, preceded by a //go:build line carrying the source's
+// constraint, including the part its file name implies unless the
+// destination's name implies the same
+func newFileHeader(file *ast.File, srcName, dstName string) string {
+	expr := headerConstraint(file)
+	if fileNameConstraint(dstName) == nil {
+		expr = and(expr, fileNameConstraint(srcName))
+	}
+	header := "package " + file.Name.Name + "\n"
+	if expr != nil {
+		header = "//go:build " + expr.String() + "\n\n" + header
+	}
+	return header
+}
+
+// constraintLabel describes a file constraint for error messages
+func constraintLabel(expr string) string {
+	if expr == "" {
+		return "no constraint"
+	}
+	return "//go:build " + expr
+}
+
+// movedDecl is a declaration cut from the source file
+type movedDecl struct {
+	name     string
+	from, to int    // Whole lines removed from the source
+	text     string // Source text, including the doc comment
+}
+
+// Move removes a declaration, with its doc comment and optionally its
+// methods, from one file and appends it to another file of the same
+// package. Imports are fixed up in both files, and either both files are
+// written or neither is.
+func Move(req MoveRequest) MoveResult {
+	if req.Path == "" || req.Symbol == "" || req.Destination == "" {
+		return moveFailure(newError(CodeInvalidRequest, nil, "Path, Symbol and Destination are required"))
+	}
+	if !strings.HasSuffix(req.Destination, ".go") {
+		return moveFailure(newError(CodeInvalidRequest, nil, "Destination must name a .go file"))
+	}
+	src, srcErr := filepath.Abs(req.Path)
+	dst, dstErr := filepath.Abs(req.Destination)
+	if srcErr != nil || dstErr != nil || filepath.Dir(src) != filepath.Dir(dst) {
+		return moveFailure(newError(CodeInvalidRequest, nil, "Destination must be in the directory of %s", req.Path))
+	}
+	if src == dst {
+		return moveFailure(newError(CodeInvalidRequest, nil, "Destination is the file declaring %s", req.Symbol))
+	}
+
+	content, err := os.ReadFile(req.Path)
+	if err != nil {
+		code := CodeReadFailed
+		if errors.Is(err, fs.ErrNotExist) {
+			code = CodeFileNotFound
+		}
+		return moveFailure(newError(code, nil, "Failed to read file: %v", err))
+	}
+	fset := token.NewFileSet()
+	file, err := parseFile(fset, req.Path, content)
+	if err != nil {
+		return moveFailure(newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse file: %v", err))
+	}
+	if generator, generated := generatedBy(file); generated && !req.AllowGenerated {
+		return moveFailure(generatedFileError(generator))
+	}
+	pkg := file.Name.Name
+
+	moved, selErr := selectMoved(fset, file, content, req)
+	if selErr != nil {
+		return moveFailure(selErr)
+	}
+
+	// Read the destination, or start it with the package clause and build
+	// constraint of the source
+	dest, err := os.ReadFile(req.Destination)
+	create := errors.Is(err, fs.ErrNotExist)
+	var destFile *ast.File
+	switch {
+	case create:
+		dest = []byte(newFileHeader(file, filepath.Base(src), filepath.Base(dst)))
+		destFile, err = parseFile(token.NewFileSet(), req.Destination, dest)
+		if err != nil {
+			return moveFailure(newError(CodeInternal, nil, "Failed to parse new destination: %v", err))
+		}
+	case err != nil:
+		return moveFailure(newError(CodeReadFailed, nil, "Failed to read file: %v", err))
+	default:
+		destFile, err = parseFile(token.NewFileSet(), req.Destination, dest)
+		if err != nil {
+			return moveFailure(newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse destination: %v", err))
+		}
+		if destFile.Name.Name != pkg {
+			return moveFailure(newError(CodeInvalidRequest, nil, "Destination belongs to package %s, not %s", destFile.Name.Name, pkg))
+		}
+		if generator, generated := generatedBy(destFile); generated && !req.AllowGenerated {
+			return moveFailure(generatedFileError(generator))
+		}
+	}
+
+	// Code moved to a file built under other constraints would be compiled
+	// for different platforms
+	from := fileConstraint(filepath.Base(src), file)
+	to := fileConstraint(filepath.Base(dst), destFile)
+	if from != to && !req.AllowConstraintMismatch {
+		return moveFailure(newError(CodeInvalidRequest, nil, "%s is built under %s but %s under %s; set AllowConstraintMismatch to move anyway",
+			filepath.Base(src), constraintLabel(from), filepath.Base(dst), constraintLabel(to)))
+	}
+
+	// Cut the declarations from the end so that earlier offsets stay valid
+	remaining := string(content)
+	texts := make([]string, len(moved))
+	names := make([]string, len(moved))
+	for i := len(moved) - 1; i >= 0; i-- {
+		remaining = remaining[:moved[i].from] + remaining[moved[i].to:]
+		texts[i], names[i] = moved[i].text, moved[i].name
+	}
+	appended := strings.TrimRight(string(dest), "\n") + "\n\n" + strings.Join(texts, "\n\n") + "\n"
+
+	// The source only loses the imports that nothing but the moved code used
+	movedFile, err := parseFile(token.NewFileSet(), "", []byte("package "+pkg+"\n\n"+strings.Join(texts, "\n\n")))
+	if err != nil {
+		return moveFailure(newError(CodeInternal, nil, "Failed to parse moved declarations: %v", err))
+	}
+	dir := filepath.Dir(req.Path)
+	srcCtx := importContext{dir: dir, only: usedPackages(movedFile, nil)}
+	newSource, _, fixErr := fixImports([]byte(remaining), srcCtx)
+	if fixErr != nil {
+		return moveFailure(fixErr)
+	}
+
+	destCtx, ctxErr := packageImportContext(dir, pkg, filepath.Base(req.Destination))
+	if ctxErr != nil {
+		return moveFailure(ctxErr)
+	}
+	// The moved code keeps the imports it had, aliases included
+	destCtx.addImports(file)
+	newDest, unresolved, fixErr := fixImports([]byte(appended), destCtx)
+	if fixErr != nil {
+		return moveFailure(fixErr)
+	}
+
+	if checkErr := recheckMove(filepath.Dir(src), map[string][]byte{src: newSource, dst: newDest}, req.Symbol); checkErr != nil {
+		return moveFailure(checkErr)
+	}
+
+	destBefore := dest
+	if create {
+		destBefore = nil
+	}
+	writes := []fileWrite{
+		{path: req.Path, before: content, after: newSource},
+		{path: req.Destination, before: destBefore, after: newDest, create: create},
+	}
+	if commitErr := commitFiles(writes); commitErr != nil {
+		return moveFailure(commitErr)
+	}
+	logger.Debug("moved declarations", "path", req.Path, "destination", req.Destination, "symbols", names)
+
+	return MoveResult{
+		Success:           true,
+		Moved:             names,
+		Files:             []FileChange{writes[0].change(), writes[1].change()},
+		UnresolvedImports: unresolved,
+	}
+}
+
+// recheckMove type-checks the package with the moved files in place and
+// fails if the move introduced type errors
+func recheckMove(dir string, overlay map[string][]byte, symbol string) *Error {
+	l := newLoader(dir, nil)
+	before, loadErr := l.loadDir(dir, true)
+	if loadErr != nil {
+		return loadErr
+	}
+	after, loadErr := l.withOverlay(overlay).loadDir(dir, true)
+	if loadErr != nil {
+		return loadErr
+	}
+	if err := addedErrors(before, after, func(msg string) string { return msg }); err != nil {
+		return newError(CodeInvalidRequest, nil, "Cannot move %s: %v", symbol, err)
+	}
+	return nil
+}
+
+// selectMoved resolves the symbol of a move to the declarations to cut,
+// adding the methods of a type when requested
+func selectMoved(fset *token.FileSet, file *ast.File, content []byte, req MoveRequest) ([]movedDecl, *Error) {
+	sel, selErr := selectSymbol(fset, file, req.Symbol)
+	if selErr != nil {
+		return nil, selErr
+	}
+
+	var typeName string
+	var first movedDecl
+	var cutErr *Error
+	switch n := sel.node.(type) {
+	case *ast.Field:
+		return nil, newError(CodeInvalidRequest, nil, "Cannot move %s: only top-level declarations can be moved", sel.name)
+	case *ast.FuncDecl:
+		first, cutErr = cutDecl(fset, file, content, sel.name, n, sel.doc, "")
+	case *ast.GenDecl:
+		if s, ok := n.Specs[0].(*ast.TypeSpec); ok {
+			typeName = s.Name.Name
+		}
+		first, cutErr = cutDecl(fset, file, content, sel.name, n, sel.doc, "")
+	case ast.Spec:
+		group := enclosingGenDecl(file, n)
+		if s, ok := n.(*ast.TypeSpec); ok {
+			typeName = s.Name.Name
+		}
+		switch {
+		case len(group.Specs) == 1:
+			// A group of one goes with its keyword and doc comment
+			doc := group.Doc
+			if sel.doc != nil {
+				doc = sel.doc
+			}
+			first, cutErr = cutDecl(fset, file, content, sel.name, group, doc, "")
+		case group.Tok == token.CONST:
+			// Implicit values and iota depend on the position in the group
+			return nil, newError(CodeInvalidRequest, nil, "Cannot move %s out of its const group", sel.name)
+		default:
+			first, cutErr = cutDecl(fset, file, content, sel.name, n, sel.doc, group.Tok.String()+" ")
+		}
+	}
+	if cutErr != nil {
+		return nil, cutErr
+	}
+	moved := []movedDecl{first}
+
+	if !req.WithMethods {
+		return moved, nil
+	}
+	if typeName == "" {
+		return nil, newError(CodeInvalidRequest, nil, "WithMethods requires a type, but %s is a %s", sel.name, sel.kind)
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 || receiverType(fn.Recv.List[0].Type) != typeName {
+			continue
+		}
+		method, err := cutDecl(fset, file, content, typeName+"."+fn.Name.Name, fn, fn.Doc, "")
+		if err != nil {
+			return nil, err
+		}
+		moved = append(moved, method)
+	}
+	// Keep the order of the source file
+	for i := 1; i < len(moved); i++ {
+		for j := i; j > 0 && moved[j].from < moved[j-1].from; j-- {
+			moved[j], moved[j-1] = moved[j-1], moved[j]
+		}
+	}
+	return moved, nil
+}
+
+// cutDecl returns the lines of node and its doc comment as a declaration,
+// prefixing the node with keyword when it is a spec taken out of a group
+func cutDecl(fset *token.FileSet, file *ast.File, content []byte, name string, node ast.Node, doc *ast.CommentGroup, keyword string) (movedDecl, *Error) {
+	start := node.Pos()
+	if doc != nil {
+		start = doc.Pos()
+	}
+	from := lineStart(fset, content, start)
+	to := lineEnd(fset, file, content, node.End())
+	startOffset := fset.Position(start).Offset
+	endOffset := fset.Position(node.End()).Offset
+	if strings.TrimSpace(string(content[from:startOffset])) != "" ||
+		!isComment(strings.TrimSpace(string(content[endOffset:to]))) {
+		return movedDecl{}, newError(CodeInvalidRequest, nil, "Cannot move %s: it shares a line with other code", name)
+	}
+
+	text := string(content[startOffset:to])
+	if keyword != "" {
+		nodeOffset := fset.Position(node.Pos()).Offset
+		if doc != nil {
+			text = string(content[startOffset:fset.Position(doc.End()).Offset]) + "\n"
+		} else {
+			text = ""
+		}
+		text += keyword + string(content[nodeOffset:to])
+	}
+	return movedDecl{name: name, from: from, to: min(to+1, len(content)), text: text}, nil
+}
+
+// isComment reports whether the rest of a line is empty or a comment
+func isComment(rest string) bool {
+	return rest == "" || strings.HasPrefix(rest, "//") || strings.HasPrefix(rest, "/*")
+}
+
+// enclosingGenDecl returns the declaration of file holding spec
+func enclosingGenDecl(file *ast.File, spec ast.Spec) *ast.GenDecl {
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok {
+			for _, s := range d.Specs {
+				if s == spec {
+					return d
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// moveFailure converts an error into a failed MoveResult
+func moveFailure(err *Error) MoveResult {
+	return MoveResult{
+		Success: false,
+		Error:   err.Message,
+		Code:    err.Code,
+		Details: err.Details,
+	}
+}
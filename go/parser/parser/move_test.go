@@ -0,0 +1,334 @@
+package parser
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMove(t *testing.T) {
+	source := `package svc
+
+import (
+	"fmt"
+	"strings"
+
+	yaml "example.com/yaml/v3"
+)
+
+// Limits of a request.
+const (
+	minSize = iota
+	maxSize
+)
+
+var (
+	// defaultName names unnamed services.
+	defaultName = "svc"
+	retries     = 3 // Attempts per request
+)
+
+// Config holds the settings.
+type Config struct {
+	Name string
+}
+
+// Load parses a config.
+func (c *Config) Load(data []byte) error { return yaml.Unmarshal(data, c) }
+
+// Describe returns a summary.
+func Describe(c Config) string { return fmt.Sprint(strings.ToUpper(c.Name), retries) }
+
+func (c Config) String() string { return c.Name }
+`
+
+	tests := []struct {
+		name       string
+		files      map[string]string
+		req        MoveRequest
+		wantCode   ErrorCode
+		wantMoved  []string
+		wantSource string
+		wantDest   string
+	}{
+		{
+			name:      "function to a new file",
+			files:     map[string]string{"service.go": source},
+			req:       MoveRequest{Symbol: "Describe", Destination: "describe.go"},
+			wantMoved: []string{"Describe"},
+			wantSource: `package svc
+
+import (
+	yaml "example.com/yaml/v3"
+)
+
+// Limits of a request.
+const (
+	minSize = iota
+	maxSize
+)
+
+var (
+	// defaultName names unnamed services.
+	defaultName = "svc"
+	retries     = 3 // Attempts per request
+)
+
+// Config holds the settings.
+type Config struct {
+	Name string
+}
+
+// Load parses a config.
+func (c *Config) Load(data []byte) error { return yaml.Unmarshal(data, c) }
+
+func (c Config) String() string { return c.Name }
+`,
+			wantDest: `package svc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Describe returns a summary.
+func Describe(c Config) string { return fmt.Sprint(strings.ToUpper(c.Name), retries) }
+`,
+		},
+		{
+			name: "type with methods to an existing file",
+			files: map[string]string{
+				"service.go": source,
+				"config.go":  "package svc\n\n// Other is unrelated.\nvar Other = 1\n",
+			},
+			req:       MoveRequest{Symbol: "Config", Destination: "config.go", WithMethods: true},
+			wantMoved: []string{"Config", "Config.Load", "Config.String"},
+			wantDest: `package svc
+
+import (
+	yaml "example.com/yaml/v3"
+)
+
+// Other is unrelated.
+var Other = 1
+
+// Config holds the settings.
+type Config struct {
+	Name string
+}
+
+// Load parses a config.
+func (c *Config) Load(data []byte) error { return yaml.Unmarshal(data, c) }
+
+func (c Config) String() string { return c.Name }
+`,
+		},
+		{
+			name:      "variable out of its group",
+			files:     map[string]string{"service.go": source},
+			req:       MoveRequest{Symbol: "retries", Destination: "retry.go"},
+			wantMoved: []string{"retries"},
+			wantDest:  "package svc\n\nvar retries = 3 // Attempts per request\n",
+		},
+		{
+			name:      "documented variable out of its group",
+			files:     map[string]string{"service.go": source},
+			req:       MoveRequest{Symbol: "defaultName", Destination: "names.go"},
+			wantMoved: []string{"defaultName"},
+			wantDest:  "package svc\n\n// defaultName names unnamed services.\nvar defaultName = \"svc\"\n",
+		},
+		{
+			name: "source keeps imports it still uses",
+			files: map[string]string{"service.go": `package svc
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"example.com/apiclient"
+)
+
+func Hello() { fmt.Println("hello") }
+
+func Roll() int { return rand.IntN(6) + client.Offset }
+`},
+			req:       MoveRequest{Symbol: "Hello", Destination: "hello.go"},
+			wantMoved: []string{"Hello"},
+			wantSource: `package svc
+
+import (
+	"math/rand/v2"
+
+	"example.com/apiclient"
+)
+
+func Roll() int { return rand.IntN(6) + client.Offset }
+`,
+			wantDest: "package svc\n\nimport (\n\t\"fmt\"\n)\n\nfunc Hello() { fmt.Println(\"hello\") }\n",
+		},
+		{
+			name: "destination binds the import name to another package",
+			files: map[string]string{
+				"service.go": "package svc\n\nimport \"math/rand/v2\"\n\nfunc Roll() int { return rand.IntN(6) }\n",
+				"random.go":  "package svc\n\nimport \"crypto/rand\"\n\nvar _ = rand.Reader\n",
+			},
+			req:      MoveRequest{Symbol: "Roll", Destination: "random.go"},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:      "new destination keeps the build constraint",
+			files:     map[string]string{"service.go": "//go:build !js\n\npackage svc\n\nfunc Hello() {}\n"},
+			req:       MoveRequest{Symbol: "Hello", Destination: "hello.go"},
+			wantMoved: []string{"Hello"},
+			wantDest:  "//go:build !js\n\npackage svc\n\nfunc Hello() {}\n",
+		},
+		{
+			name: "destination built under another constraint",
+			files: map[string]string{
+				"service.go": "//go:build !js\n\npackage svc\n\nfunc Hello() {}\n",
+				"hello.go":   "package svc\n",
+			},
+			req:      MoveRequest{Symbol: "Hello", Destination: "hello.go"},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "new destination named for another platform",
+			files:    map[string]string{"service.go": "package svc\n\nfunc Hello() {}\n"},
+			req:      MoveRequest{Symbol: "Hello", Destination: "hello_plan9.go"},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name: "constraint mismatch allowed",
+			files: map[string]string{
+				"service.go": "//go:build !js\n\npackage svc\n\nfunc Hello() {}\n",
+				"hello.go":   "package svc\n",
+			},
+			req:       MoveRequest{Symbol: "Hello", Destination: "hello.go", AllowConstraintMismatch: true},
+			wantMoved: []string{"Hello"},
+			wantDest:  "package svc\n\nfunc Hello() {}\n",
+		},
+		{
+			name:     "constant out of its group",
+			files:    map[string]string{"service.go": source},
+			req:      MoveRequest{Symbol: "maxSize", Destination: "limits.go"},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "field",
+			files:    map[string]string{"service.go": source},
+			req:      MoveRequest{Symbol: "Config.Name", Destination: "config.go"},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "methods of a function",
+			files:    map[string]string{"service.go": source},
+			req:      MoveRequest{Symbol: "Describe", Destination: "describe.go", WithMethods: true},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "destination in another package",
+			files:    map[string]string{"service.go": source, "svc_test.go": "package svc_test\n"},
+			req:      MoveRequest{Symbol: "Describe", Destination: "svc_test.go"},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "destination in another directory",
+			files:    map[string]string{"service.go": source},
+			req:      MoveRequest{Symbol: "Describe", Destination: filepath.Join("sub", "describe.go")},
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name: "generated destination",
+			files: map[string]string{
+				"service.go": source,
+				"gen.go":     "// Code generated by stringer. DO NOT EDIT.\n\npackage svc\n",
+			},
+			req:      MoveRequest{Symbol: "Describe", Destination: "gen.go"},
+			wantCode: CodeGeneratedFile,
+		},
+		{
+			name:     "missing symbol",
+			files:    map[string]string{"service.go": source},
+			req:      MoveRequest{Symbol: "Missing", Destination: "describe.go"},
+			wantCode: CodeSymbolNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writePackage(t, tt.files)
+			req := tt.req
+			req.Path = filepath.Join(dir, "service.go")
+			req.Destination = filepath.Join(dir, req.Destination)
+			result := Move(req)
+			if result.Code != tt.wantCode {
+				t.Fatalf("Move() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if tt.wantCode != "" {
+				// Nothing may be written when the move fails
+				if got, _ := os.ReadFile(req.Path); string(got) != tt.files["service.go"] {
+					t.Errorf("Source changed by failed move:\n%s", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(result.Moved, tt.wantMoved) {
+				t.Errorf("Move() moved = %v, want %v", result.Moved, tt.wantMoved)
+			}
+			if len(result.Files) != 2 {
+				t.Fatalf("Move() files = %+v, want source and destination", result.Files)
+			}
+			if _, exists := tt.files[tt.req.Destination]; result.Files[1].Created == exists {
+				t.Errorf("Move() created = %v, want %v", result.Files[1].Created, !exists)
+			}
+			for i, want := range []string{tt.wantSource, tt.wantDest} {
+				file := result.Files[i]
+				written, err := os.ReadFile(file.Path)
+				if err != nil || string(written) != file.Content {
+					t.Errorf("File %s = %q, want %q (error: %v)", file.Path, written, file.Content, err)
+				}
+				if want != "" && file.Content != want {
+					t.Errorf("File %s =\n%s\nwant:\n%s", file.Path, file.Content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewFileHeader(t *testing.T) {
+	tests := []struct {
+		src, srcName, dstName string
+		want                  string
+	}{
+		{"package svc\n", "service.go", "hello.go", "package svc\n"},
+		{"//go:build cgo\n\npackage svc\n", "service.go", "hello.go", "//go:build cgo\n\npackage svc\n"},
+		// The file name of the source no longer applies to the destination
+		{"//go:build cgo\n\npackage svc\n", "service_linux.go", "hello.go", "//go:build cgo && linux\n\npackage svc\n"},
+		{"package svc\n", "service_linux.go", "hello_linux.go", "package svc\n"},
+	}
+	for _, tt := range tests {
+		file, err := parseFile(token.NewFileSet(), tt.srcName, tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := newFileHeader(file, tt.srcName, tt.dstName); got != tt.want {
+			t.Errorf("newFileHeader(%s, %s) = %q, want %q", tt.srcName, tt.dstName, got, tt.want)
+		}
+	}
+}
+
+func TestCommitFilesDetectsChanges(t *testing.T) {
+	dir := writePackage(t, map[string]string{"a.go": "package a\n", "b.go": "package a\n"})
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	err := commitFiles([]fileWrite{
+		{path: a, before: []byte("package a\n"), after: []byte("package a\n\nvar A int\n")},
+		{path: b, before: []byte("package b\n"), after: []byte("package a\n\nvar B int\n")},
+	})
+	if err == nil || err.Code != CodeFileChanged {
+		t.Fatalf("commitFiles() error = %v, want %s", err, CodeFileChanged)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "package a\n" {
+		t.Errorf("a.go = %q, want it untouched", got)
+	}
+}
@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PackageOptions controls which files ParsePackage considers
+type PackageOptions struct {
+	ParseOptions
+
+	// IncludeTests adds _test.go files, including external test packages
+	IncludeTests bool `json:"includeTests,omitempty"`
+	// IgnoreBuildConstraints parses every .go file regardless of
+	// //go:build lines and _GOOS/_GOARCH file name suffixes
+	IgnoreBuildConstraints bool `json:"ignoreBuildConstraints,omitempty"`
+	// GOOS, GOARCH and Tags select the build configuration files are
+	// matched against. Empty values default to the host configuration.
+	GOOS   string   `json:"goos,omitempty"`
+	GOARCH string   `json:"goarch,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	// AllVariants reports files for every build configuration, marking
+	// those excluded by the requested one instead of leaving them out
+	AllVariants bool `json:"allVariants,omitempty"`
+}
+
+// FileSymbols holds the symbols declared in a single file of a package
+type FileSymbols struct {
+	Path       string   `json:"path"`
+	Package    string   `json:"package"`
+	Constraint string   `json:"constraint,omitempty"`
+	Excluded   bool     `json:"excluded,omitempty"`
+	Symbols    []Symbol `json:"symbols,omitempty"`
+}
+
+// PackageResult represents the result of parsing a package directory
+type PackageResult struct {
+	Success bool          `json:"success"`
+	Package string        `json:"package,omitempty"`
+	Files   []FileSymbols `json:"files,omitempty"`
+	Merged  []Symbol      `json:"merged,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// packageFile is a candidate file of a package directory
+type packageFile struct {
+	name     string
+	excluded bool
+}
+
+// ParsePackage parses the Go files of a package directory and returns the
+// symbols of each file along with a merged view in which methods are
+// attached to their receiver types across files
+func ParsePackage(dir string, opts PackageOptions) (PackageResult, error) {
+	candidates, err := packageFiles(dir, opts)
+	if err != nil {
+		return PackageResult{
+			Success: false,
+			Error:   "Failed to read package directory",
+		}, err
+	}
+	if len(candidates) == 0 {
+		return PackageResult{
+			Success: false,
+			Error:   "No Go files found in directory",
+		}, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	fset := token.NewFileSet()
+	var files []FileSymbols
+	for _, candidate := range candidates {
+		path := filepath.Join(dir, candidate.name)
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return PackageResult{
+				Success: false,
+				Error:   "Failed to parse file",
+			}, err
+		}
+		files = append(files, FileSymbols{
+			Path:       path,
+			Package:    file.Name.Name,
+			Constraint: fileConstraint(candidate.name, file),
+			Excluded:   candidate.excluded,
+			Symbols:    fileSymbols(fset, file, opts.ParseOptions),
+		})
+	}
+
+	// The package is named by the files selected for the build. Excluded
+	// variants belonging to another package, such as //go:build ignore
+	// tools, are dropped rather than reported as a conflict.
+	result := PackageResult{Success: true}
+	for _, f := range files {
+		if f.Excluded || strings.HasSuffix(f.Package, "_test") {
+			continue
+		}
+		if result.Package != "" && result.Package != f.Package {
+			return PackageResult{
+				Success: false,
+				Error:   "Multiple packages in directory",
+			}, fmt.Errorf("found packages %s and %s in %s", result.Package, f.Package, dir)
+		}
+		result.Package = f.Package
+	}
+	// A directory holding only an external test package is named after it
+	if result.Package == "" {
+		result.Package = files[0].Package
+	}
+	for _, f := range files {
+		if f.Excluded && f.Package != result.Package && f.Package != result.Package+"_test" {
+			continue
+		}
+		result.Files = append(result.Files, f)
+	}
+	result.Merged = mergeSymbols(result.Files)
+
+	return result, nil
+}
+
+// packageFiles lists the Go files of dir that belong to the package under
+// opts. With AllVariants, files excluded by the build configuration are
+// returned too and marked as such.
+func packageFiles(dir string, opts PackageOptions) ([]packageFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxt := build.Default
+	if opts.GOOS != "" {
+		ctxt.GOOS = opts.GOOS
+	}
+	if opts.GOARCH != "" {
+		ctxt.GOARCH = opts.GOARCH
+	}
+	if opts.GOOS != "" || opts.GOARCH != "" {
+		// Cross-configuration cgo files cannot be built without a matching toolchain
+		ctxt.CgoEnabled = ctxt.GOOS == build.Default.GOOS && ctxt.GOARCH == build.Default.GOARCH && build.Default.CgoEnabled
+	}
+	ctxt.BuildTags = opts.Tags
+
+	var files []packageFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		// Match the go tool in ignoring files starting with _ or .
+		if strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if !opts.IncludeTests && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		excluded := false
+		if !opts.IgnoreBuildConstraints {
+			match, err := ctxt.MatchFile(dir, name)
+			if err != nil {
+				return nil, err
+			}
+			if !match && !opts.AllVariants {
+				continue
+			}
+			excluded = !match
+		}
+		files = append(files, packageFile{name: name, excluded: excluded})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
+
+// mergeSymbols combines per-file symbols into a single package view, moving
+// methods under the type they are declared on and labelling each symbol
+// with the file and build constraint it came from. Methods whose receiver type
+// is not declared in the package stay at the top level.
+func mergeSymbols(files []FileSymbols) []Symbol {
+	var merged []Symbol
+	typeIndex := make(map[string]int)
+
+	for _, f := range files {
+		for _, symbol := range f.Symbols {
+			if symbol.Receiver != "" {
+				continue
+			}
+			symbol.File = f.Path
+			symbol.Constraint = f.Constraint
+			if isTypeKind(symbol.Kind) {
+				// The first variant of a type declared in several
+				// constrained files collects the methods
+				key := f.Package + "." + symbol.Name
+				if _, ok := typeIndex[key]; !ok {
+					typeIndex[key] = len(merged)
+				}
+			}
+			merged = append(merged, symbol)
+		}
+	}
+
+	for _, f := range files {
+		for _, symbol := range f.Symbols {
+			if symbol.Receiver == "" {
+				continue
+			}
+			symbol.File = f.Path
+			symbol.Constraint = f.Constraint
+			i, ok := typeIndex[f.Package+"."+symbol.Receiver]
+			if !ok {
+				merged = append(merged, symbol)
+				continue
+			}
+			method := symbol
+			method.Kind = "method"
+			// Copy before appending so per-file children are not aliased
+			children := make([]Symbol, len(merged[i].Children), len(merged[i].Children)+1)
+			copy(children, merged[i].Children)
+			merged[i].Children = append(children, method)
+		}
+	}
+
+	return merged
+}
+
+// isTypeKind reports whether kind names a type declaration
+func isTypeKind(kind string) bool {
+	return kind == "type" || kind == "struct" || kind == "interface"
+}
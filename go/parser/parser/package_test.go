@@ -0,0 +1,240 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePackage creates the given files in a fresh temporary directory
+func writePackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestParsePackage(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"service.go": `package svc
+
+// Service processes requests
+type Service struct {
+	Name string
+}
+
+func New() *Service { return &Service{} }
+`,
+		"process.go": `package svc
+
+// Process handles a request
+func (s *Service) Process() error { return nil }
+
+func (o orphan) Run() {}
+`,
+		"ignored.go": `//go:build ignore
+
+package main
+
+func main() {}
+`,
+		"service_test.go": `package svc
+
+func helper() {}
+`,
+		"external_test.go": `package svc_test
+
+func TestExternal() {}
+`,
+	})
+
+	result, err := ParsePackage(dir, PackageOptions{})
+	if err != nil {
+		t.Fatalf("ParsePackage failed: %v", err)
+	}
+	if result.Package != "svc" {
+		t.Errorf("Package = %q, want %q", result.Package, "svc")
+	}
+	if len(result.Files) != 2 {
+		t.Fatalf("Files = %d, want 2 (tests and ignored files excluded)", len(result.Files))
+	}
+
+	var service *Symbol
+	for i, symbol := range result.Merged {
+		switch symbol.Name {
+		case "Service":
+			service = &result.Merged[i]
+		case "Process":
+			t.Error("Process should be attached to Service, found at top level")
+		}
+	}
+	if service == nil {
+		t.Fatal("Service not found in merged view")
+	}
+	var process *Symbol
+	for i, child := range service.Children {
+		if child.Name == "Process" {
+			process = &service.Children[i]
+		}
+	}
+	if process == nil {
+		t.Fatalf("Process not attached to Service: %v", service.Children)
+	}
+	if process.Kind != "method" || process.Receiver != "Service" {
+		t.Errorf("Process: kind = %q, receiver = %q", process.Kind, process.Receiver)
+	}
+	if filepath.Base(process.File) != "process.go" {
+		t.Errorf("Process file = %q, want process.go", process.File)
+	}
+
+	// Per-file symbols are not affected by merging
+	for _, f := range result.Files {
+		if filepath.Base(f.Path) != "service.go" {
+			continue
+		}
+		for _, symbol := range f.Symbols {
+			if symbol.Name == "Service" && len(symbol.Children) != 1 {
+				t.Errorf("Service children in service.go = %v, want only the Name field", symbol.Children)
+			}
+		}
+	}
+
+	// Test files are included on request
+	result, err = ParsePackage(dir, PackageOptions{IncludeTests: true})
+	if err != nil {
+		t.Fatalf("ParsePackage failed: %v", err)
+	}
+	if len(result.Files) != 4 {
+		t.Errorf("Files = %d, want 4 with tests", len(result.Files))
+	}
+	if result.Package != "svc" {
+		t.Errorf("Package = %q, want %q", result.Package, "svc")
+	}
+
+	// Ignoring build constraints pulls in the package main file
+	_, err = ParsePackage(dir, PackageOptions{IgnoreBuildConstraints: true})
+	if err == nil {
+		t.Error("Expected multiple package error when ignoring build constraints")
+	}
+}
+
+func TestParsePackageBuildConstraints(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"common.go": `package sys
+
+type Handle struct{}
+`,
+		"handle_linux.go": `package sys
+
+func (h Handle) Close() error { return nil }
+`,
+		"handle_windows.go": `package sys
+
+func (h Handle) Close() error { return nil }
+`,
+		"debug.go": `//go:build debug && !race
+
+package sys
+
+func Trace() {}
+`,
+		"tool.go": `//go:build ignore
+
+package main
+`,
+	})
+
+	constraints := func(result PackageResult) map[string]string {
+		m := make(map[string]string)
+		for _, f := range result.Files {
+			m[filepath.Base(f.Path)] = f.Constraint
+		}
+		return m
+	}
+
+	result, err := ParsePackage(dir, PackageOptions{GOOS: "linux", GOARCH: "amd64"})
+	if err != nil {
+		t.Fatalf("ParsePackage failed: %v", err)
+	}
+	got := constraints(result)
+	if len(got) != 2 || got["handle_linux.go"] != "linux" || got["common.go"] != "" {
+		t.Errorf("linux files = %v, want common.go and handle_linux.go", got)
+	}
+
+	result, err = ParsePackage(dir, PackageOptions{GOOS: "windows", GOARCH: "amd64", Tags: []string{"debug"}})
+	if err != nil {
+		t.Fatalf("ParsePackage failed: %v", err)
+	}
+	got = constraints(result)
+	if len(got) != 3 || got["debug.go"] != "debug && !race" {
+		t.Errorf("windows debug files = %v, want common.go, debug.go and handle_windows.go", got)
+	}
+
+	result, err = ParsePackage(dir, PackageOptions{GOOS: "linux", GOARCH: "amd64", AllVariants: true})
+	if err != nil {
+		t.Fatalf("ParsePackage failed: %v", err)
+	}
+	if result.Package != "sys" {
+		t.Errorf("Package = %q, want %q", result.Package, "sys")
+	}
+	excluded := make(map[string]bool)
+	for _, f := range result.Files {
+		excluded[filepath.Base(f.Path)] = f.Excluded
+	}
+	want := map[string]bool{
+		"common.go":         false,
+		"handle_linux.go":   false,
+		"handle_windows.go": true,
+		"debug.go":          true,
+	}
+	if len(excluded) != len(want) {
+		t.Errorf("all variant files = %v, want %v", excluded, want)
+	}
+	for name, wantExcluded := range want {
+		if excluded[name] != wantExcluded {
+			t.Errorf("%s: excluded = %v, want %v", name, excluded[name], wantExcluded)
+		}
+	}
+
+	// Both Close variants are attached to Handle, labelled by constraint
+	labels := make(map[string]bool)
+	for _, symbol := range result.Merged {
+		if symbol.Name != "Handle" {
+			continue
+		}
+		for _, child := range symbol.Children {
+			if child.Name == "Close" {
+				labels[child.Constraint] = true
+			}
+		}
+	}
+	if !labels["linux"] || !labels["windows"] {
+		t.Errorf("Close variants = %v, want linux and windows", labels)
+	}
+}
+
+func TestFileNameConstraint(t *testing.T) {
+	tests := map[string]string{
+		"linux.go":              "",
+		"file.go":               "",
+		"file_linux.go":         "linux",
+		"file_arm64.go":         "arm64",
+		"file_darwin_arm64.go":  "darwin && arm64",
+		"file_windows_test.go":  "windows",
+		"file_unknown_amd64.go": "amd64",
+		"file_linux_unknown.go": "",
+	}
+	for name, want := range tests {
+		got := ""
+		if expr := fileNameConstraint(name); expr != nil {
+			got = expr.String()
+		}
+		if got != want {
+			t.Errorf("fileNameConstraint(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -9,19 +9,23 @@ import (
 
 // Symbol represents a code symbol with its metadata
 type Symbol struct {
-	Name     string   `json:"name"`
-	Kind     string   `json:"kind"`
-	Start    int      `json:"start"`
-	End      int      `json:"end"`
-	Doc      string   `json:"doc,omitempty"`
-	Children []Symbol `json:"children,omitempty"`
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	Receiver   string   `json:"receiver,omitempty"`
+	File       string   `json:"file,omitempty"`
+	Constraint string   `json:"constraint,omitempty"`
+	Start      int      `json:"start"`
+	End        int      `json:"end"`
+	Doc        string   `json:"doc,omitempty"`
+	Children   []Symbol `json:"children,omitempty"`
 }
 
 // ParseResult represents the result of parsing a Go file
 type ParseResult struct {
-	Success bool     `json:"success"`
-	Symbols []Symbol `json:"symbols,omitempty"`
-	Error   string   `json:"error,omitempty"`
+	Success bool      `json:"success"`
+	File    *FileInfo `json:"file,omitempty"`
+	Symbols []Symbol  `json:"symbols,omitempty"`
+	Error   string    `json:"error,omitempty"`
 }
 
 // cleanDoc removes extra whitespace but preserves the final newline
@@ -37,10 +41,35 @@ func cleanDoc(doc string) string {
 	return strings.Join(cleaned, " ") + "\n"
 }
 
+// ParseOptions controls how much detail Parse reports
+type ParseOptions struct {
+	// Depth enables symbols declared inside function bodies. Zero reports
+	// top-level declarations only, 1 adds local types, named closures and
+	// t.Run subtests of each function, and every further level descends
+	// into those closures and subtests.
+	Depth int `json:"depth,omitempty"`
+}
+
 // Parse parses a Go file and returns its symbols
 func Parse(path string) (ParseResult, error) {
+	return ParseWithOptions(path, ParseOptions{})
+}
+
+// ParseWithOptions parses a Go file and returns its symbols using the given options
+func ParseWithOptions(path string, opts ParseOptions) (ParseResult, error) {
+	return ParseSource(path, nil, opts)
+}
+
+// ParseSource parses Go source held in memory, such as an unsaved editor
+// buffer, and returns its symbols. The path is used for positions and file
+// name build constraints. If src is nil the file is read from path.
+func ParseSource(path string, src []byte, opts ParseOptions) (ParseResult, error) {
 	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	var source interface{}
+	if src != nil {
+		source = src
+	}
+	file, err := parser.ParseFile(fset, path, source, parser.ParseComments)
 	if err != nil {
 		return ParseResult{
 			Success: false,
@@ -48,6 +77,15 @@ func Parse(path string) (ParseResult, error) {
 		}, err
 	}
 
+	return ParseResult{
+		Success: true,
+		File:    fileInfo(fset, path, file),
+		Symbols: fileSymbols(fset, file, opts),
+	}, nil
+}
+
+// fileSymbols extracts the symbols declared in a parsed file
+func fileSymbols(fset *token.FileSet, file *ast.File, opts ParseOptions) []Symbol {
 	var symbols []Symbol
 
 	// Extract declarations
@@ -63,9 +101,15 @@ func Parse(path string) (ParseResult, error) {
 				Start: pos.Offset,
 				End:   end.Offset,
 			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				symbol.Receiver = receiverType(d.Recv.List[0].Type)
+			}
 			if d.Doc != nil {
 				symbol.Doc = cleanDoc(d.Doc.Text())
 			}
+			if opts.Depth > 0 && d.Body != nil {
+				symbol.Children = localSymbols(fset, d.Body, opts.Depth)
+			}
 			symbols = append(symbols, symbol)
 
 		case *ast.GenDecl:
@@ -73,53 +117,7 @@ func Parse(path string) (ParseResult, error) {
 			for _, spec := range d.Specs {
 				switch s := spec.(type) {
 				case *ast.TypeSpec:
-					// Type declaration
-					pos := fset.Position(s.Pos())
-					end := fset.Position(s.End())
-					symbol := Symbol{
-						Name:  s.Name.Name,
-						Kind:  "type",
-						Start: pos.Offset,
-						End:   end.Offset,
-					}
-					if d.Doc != nil {
-						symbol.Doc = cleanDoc(d.Doc.Text())
-					}
-
-					// Handle struct and interface types
-					switch t := s.Type.(type) {
-					case *ast.StructType:
-						symbol.Kind = "struct"
-						// Add struct fields as children
-						for _, field := range t.Fields.List {
-							for _, name := range field.Names {
-								fieldPos := fset.Position(field.Pos())
-								fieldEnd := fset.Position(field.End())
-								symbol.Children = append(symbol.Children, Symbol{
-									Name:  name.Name,
-									Kind:  "field",
-									Start: fieldPos.Offset,
-									End:   fieldEnd.Offset,
-								})
-							}
-						}
-					case *ast.InterfaceType:
-						symbol.Kind = "interface"
-						// Add interface methods as children
-						for _, method := range t.Methods.List {
-							for _, name := range method.Names {
-								methodPos := fset.Position(method.Pos())
-								methodEnd := fset.Position(method.End())
-								symbol.Children = append(symbol.Children, Symbol{
-									Name:  name.Name,
-									Kind:  "method",
-									Start: methodPos.Offset,
-									End:   methodEnd.Offset,
-								})
-							}
-						}
-					}
-					symbols = append(symbols, symbol)
+					symbols = append(symbols, typeSymbol(fset, d, s))
 
 				case *ast.ValueSpec:
 					// Variable and constant declarations
@@ -146,8 +144,74 @@ func Parse(path string) (ParseResult, error) {
 		}
 	}
 
-	return ParseResult{
-		Success: true,
-		Symbols: symbols,
-	}, nil
+	return symbols
+}
+
+// typeSymbol builds the symbol for a type declaration, including struct fields
+// and interface methods as children
+func typeSymbol(fset *token.FileSet, d *ast.GenDecl, s *ast.TypeSpec) Symbol {
+	pos := fset.Position(s.Pos())
+	end := fset.Position(s.End())
+	symbol := Symbol{
+		Name:  s.Name.Name,
+		Kind:  "type",
+		Start: pos.Offset,
+		End:   end.Offset,
+	}
+	if d.Doc != nil {
+		symbol.Doc = cleanDoc(d.Doc.Text())
+	}
+
+	// Handle struct and interface types
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		symbol.Kind = "struct"
+		// Add struct fields as children
+		for _, field := range t.Fields.List {
+			for _, name := range field.Names {
+				fieldPos := fset.Position(field.Pos())
+				fieldEnd := fset.Position(field.End())
+				symbol.Children = append(symbol.Children, Symbol{
+					Name:  name.Name,
+					Kind:  "field",
+					Start: fieldPos.Offset,
+					End:   fieldEnd.Offset,
+				})
+			}
+		}
+	case *ast.InterfaceType:
+		symbol.Kind = "interface"
+		// Add interface methods as children
+		for _, method := range t.Methods.List {
+			for _, name := range method.Names {
+				methodPos := fset.Position(method.Pos())
+				methodEnd := fset.Position(method.End())
+				symbol.Children = append(symbol.Children, Symbol{
+					Name:  name.Name,
+					Kind:  "method",
+					Start: methodPos.Offset,
+					End:   methodEnd.Offset,
+				})
+			}
+		}
+	}
+	return symbol
+}
+
+// receiverType returns the base type name of a method receiver, stripping
+// pointers and type parameters
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.ParenExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
 }
@@ -3,6 +3,7 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -146,3 +147,186 @@ func TestParseErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLocalSymbols(t *testing.T) {
+	content := `package test
+
+import "testing"
+
+func Serve() {
+	type request struct {
+		ID int
+	}
+	handler := func(r request) {
+		type inner int
+	}
+	var fallback = func() {}
+	go func() {
+		type background struct{}
+	}()
+	handler(request{})
+	fallback()
+}
+
+func TestServe(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		t.Run("nested", func(t *testing.T) {})
+	})
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {})
+	}
+}`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Default options report top-level declarations only
+	result, err := Parse(testFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	for _, symbol := range result.Symbols {
+		if len(symbol.Children) != 0 {
+			t.Errorf("Symbol %s: expected no children by default, got %d", symbol.Name, len(symbol.Children))
+		}
+	}
+
+	// kinds flattens a symbol's children into name -> kind
+	kinds := func(symbols []Symbol) map[string]string {
+		m := make(map[string]string)
+		for _, s := range symbols {
+			m[s.Name] = s.Kind
+		}
+		return m
+	}
+
+	result, err = ParseWithOptions(testFile, ParseOptions{Depth: 1})
+	if err != nil {
+		t.Fatalf("ParseWithOptions failed: %v", err)
+	}
+	for _, symbol := range result.Symbols {
+		got := kinds(symbol.Children)
+		switch symbol.Name {
+		case "Serve":
+			want := map[string]string{
+				"request":    "struct",
+				"handler":    "closure",
+				"fallback":   "closure",
+				"background": "struct",
+			}
+			if len(got) != len(want) {
+				t.Errorf("Serve children = %v, want %v", got, want)
+			}
+			for name, kind := range want {
+				if got[name] != kind {
+					t.Errorf("Serve child %s: kind = %q, want %q", name, got[name], kind)
+				}
+			}
+			for _, child := range symbol.Children {
+				if child.Name == "handler" && len(child.Children) != 0 {
+					t.Errorf("handler: expected no children at depth 1, got %v", child.Children)
+				}
+			}
+		case "TestServe":
+			want := map[string]string{"ok": "subtest", "tt.name": "subtest"}
+			if len(got) != len(want) {
+				t.Errorf("TestServe children = %v, want %v", got, want)
+			}
+			for name, kind := range want {
+				if got[name] != kind {
+					t.Errorf("TestServe child %s: kind = %q, want %q", name, got[name], kind)
+				}
+			}
+		}
+	}
+
+	result, err = ParseWithOptions(testFile, ParseOptions{Depth: 2})
+	if err != nil {
+		t.Fatalf("ParseWithOptions failed: %v", err)
+	}
+	for _, symbol := range result.Symbols {
+		for _, child := range symbol.Children {
+			switch child.Name {
+			case "handler":
+				if len(child.Children) != 1 || child.Children[0].Name != "inner" {
+					t.Errorf("handler children = %v, want [inner]", child.Children)
+				}
+			case "ok":
+				if len(child.Children) != 1 || child.Children[0].Name != "nested" {
+					t.Errorf("ok children = %v, want [nested]", child.Children)
+				}
+			}
+		}
+	}
+}
+
+func TestParseFileInfo(t *testing.T) {
+	content := `// Code generated by protoc-gen-go. DO NOT EDIT.
+
+//go:build linux || darwin
+
+// Package api exposes the service API.
+package api
+
+import (
+	"fmt"
+	str "strings"
+	_ "embed"
+)
+
+//go:generate stringer -type=Kind
+type Kind int
+
+func Describe(k Kind) string { return fmt.Sprint(str.ToLower("x"), k) }`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "api_amd64.go")
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	result, err := Parse(testFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	info := result.File
+	if info == nil {
+		t.Fatal("File metadata missing")
+	}
+	if info.Package != "api" {
+		t.Errorf("Package = %q, want %q", info.Package, "api")
+	}
+	if info.PackageDoc != "Package api exposes the service API.\n" {
+		t.Errorf("PackageDoc = %q", info.PackageDoc)
+	}
+	if !info.Generated || info.Generator != "protoc-gen-go" {
+		t.Errorf("Generated = %v, Generator = %q, want protoc-gen-go", info.Generated, info.Generator)
+	}
+	if info.BuildConstraint != "(linux || darwin) && amd64" {
+		t.Errorf("BuildConstraint = %q", info.BuildConstraint)
+	}
+	if len(info.GoGenerate) != 1 || info.GoGenerate[0] != "stringer -type=Kind" {
+		t.Errorf("GoGenerate = %v", info.GoGenerate)
+	}
+
+	wantImports := []Import{
+		{Path: "fmt"},
+		{Path: "strings", Name: "str"},
+		{Path: "embed", Name: "_"},
+	}
+	if len(info.Imports) != len(wantImports) {
+		t.Fatalf("Imports = %v, want %v", info.Imports, wantImports)
+	}
+	for i, want := range wantImports {
+		got := info.Imports[i]
+		if got.Path != want.Path || got.Name != want.Name {
+			t.Errorf("Import %d = %+v, want path %q name %q", i, got, want.Path, want.Name)
+		}
+		if content[got.Start:got.End] == "" || !strings.Contains(content[got.Start:got.End], want.Path) {
+			t.Errorf("Import %d range %d-%d does not cover %q", i, got.Start, got.End, want.Path)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"strings"
+)
+
+// Insert positions that place new declarations without an anchor symbol, or
+// next to the methods of a type
+const (
+	positionStart         = "start"            // Directly after the package clause, before any imports
+	positionAfterImports  = "after-imports"    // After the last import declaration
+	positionEnd           = "end"              // At the end of the file
+	positionFirstMethodOf = "first-method-of:" // Followed by a type name: before its first method
+	positionLastMethodOf  = "last-method-of:"  // Followed by a type name: after its last method
+)
+
+// IsFilePosition reports whether an insert position is one of the positions
+// that need no RelativeToSymbol
+func IsFilePosition(position string) bool {
+	switch {
+	case position == positionStart, position == positionAfterImports, position == positionEnd:
+		return true
+	case strings.HasPrefix(position, positionFirstMethodOf):
+		return position != positionFirstMethodOf
+	case strings.HasPrefix(position, positionLastMethodOf):
+		return position != positionLastMethodOf
+	}
+	return false
+}
+
+// insertAtPosition inserts new declarations at a file position by splicing
+// the source text, so that the rest of the file keeps its comments
+func insertAtPosition(fset *token.FileSet, file *ast.File, content []byte, req EditRequest) EditResult {
+	newContent := strings.TrimSpace(req.Content)
+	parsed, err := parseFile(token.NewFileSet(), "", fmt.Sprintf("package %s\n%s", file.Name.Name, newContent))
+	if err != nil {
+		// Report positions relative to the content, not the package clause added above
+		return failure(newError(CodeContentSyntax, syntaxDetails(err, 1), "Failed to parse new content: %v", err))
+	}
+	if len(parsed.Decls) == 0 {
+		return failure(newError(CodeContentSyntax, nil, "No declaration found in new content"))
+	}
+	imports := 0
+	for _, decl := range parsed.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			imports++
+		}
+	}
+
+	position := req.Insert.Position
+	lastImport := token.NoPos
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			lastImport = d.End()
+		}
+	}
+	switch {
+	case imports > 0 && imports < len(parsed.Decls):
+		return failure(newError(CodeInvalidRequest, nil, "New content mixes import declarations with other declarations"))
+	case imports > 0 && position != positionStart && position != positionAfterImports:
+		return failure(newError(CodeInvalidRequest, nil, "Import declarations can only be inserted at %q or %q", positionStart, positionAfterImports))
+	case imports == 0 && position == positionStart && lastImport.IsValid():
+		return failure(newError(CodeInvalidRequest, nil, "Declarations cannot precede the imports: use %q", positionAfterImports))
+	}
+
+	var at int
+	before := false
+	switch {
+	case position == positionStart:
+		at = lineEnd(fset, file, content, file.Name.End())
+	case position == positionAfterImports:
+		at = lineEnd(fset, file, content, file.Name.End())
+		if lastImport.IsValid() {
+			at = lineEnd(fset, file, content, lastImport)
+		}
+	case position == positionEnd:
+		at = len(content)
+	default:
+		typeName := position[strings.Index(position, ":")+1:]
+		var typeDecl ast.Node
+		var methods []*ast.FuncDecl
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv != nil && len(d.Recv.List) > 0 && receiverType(d.Recv.List[0].Type) == typeName {
+					methods = append(methods, d)
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if s, ok := spec.(*ast.TypeSpec); ok && s.Name.Name == typeName {
+						typeDecl = d
+					}
+				}
+			}
+		}
+		switch {
+		case len(methods) > 0 && strings.HasPrefix(position, positionFirstMethodOf):
+			first := methods[0]
+			pos := first.Pos()
+			if first.Doc != nil {
+				pos = first.Doc.Pos()
+			}
+			at, before = fset.Position(pos).Offset, true
+		case len(methods) > 0:
+			at = lineEnd(fset, file, content, methods[len(methods)-1].End())
+		case typeDecl != nil:
+			// Without methods, the first method follows the type itself
+			at = lineEnd(fset, file, content, typeDecl.End())
+		default:
+			return failure(notFoundError(fset, file, typeName, kindTypes))
+		}
+	}
+
+	var edited string
+	if before {
+		edited = string(content[:at]) + newContent + "\n\n" + string(content[at:])
+	} else {
+		edited = string(content[:at]) + "\n\n" + newContent + "\n" + string(content[at:])
+	}
+	formatted, fmtErr := format.Source([]byte(edited))
+	if fmtErr != nil {
+		return failure(newError(CodeFormatFailed, nil, "Failed to format modified code: %v", fmtErr))
+	}
+	return EditResult{
+		Success: true,
+		Content: string(formatted),
+	}
+}
+
+// lineEnd returns the offset of the end of the line containing pos, after
+// any comment trailing it on that line
+func lineEnd(fset *token.FileSet, file *ast.File, content []byte, pos token.Pos) int {
+	line := fset.Position(pos).Line
+	end := fset.Position(pos).Offset
+	for _, c := range file.Comments {
+		if c.Pos() >= pos && fset.Position(c.Pos()).Line == line {
+			end = fset.Position(c.End()).Offset
+		}
+	}
+	for end < len(content) && content[end] != '\n' {
+		end++
+	}
+	return end
+}
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInsertAtPosition(t *testing.T) {
+	withImports := `// Package test does things.
+package test // trailing
+
+import "fmt"
+
+// Service does work.
+type Service struct{}
+
+// Start starts.
+func (s *Service) Start() {}
+
+func (s *Service) Stop() {}
+
+type Empty struct{}
+
+func Run() { fmt.Println() }
+`
+
+	tests := []struct {
+		name     string
+		initial  string
+		position string
+		content  string
+		wantCode ErrorCode
+		want     string
+	}{
+		{
+			name:     "start of file without imports",
+			initial:  "package test\n",
+			position: "start",
+			content:  "func First() {}",
+			want:     "package test\n\nfunc First() {}\n",
+		},
+		{
+			name:     "start of file with imports",
+			initial:  withImports,
+			position: "start",
+			content:  "func First() {}",
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "import at start",
+			initial:  withImports,
+			position: "start",
+			content:  `import "os"`,
+			want:     "package test // trailing\n\nimport \"os\"\n\nimport \"fmt\"\n",
+		},
+		{
+			name:     "after imports",
+			initial:  withImports,
+			position: "after-imports",
+			content:  "var x = 1",
+			want:     "import \"fmt\"\n\nvar x = 1\n\n// Service does work.",
+		},
+		{
+			name:     "after imports in file without imports",
+			initial:  "package test\n\nfunc Run() {}\n",
+			position: "after-imports",
+			content:  "const c = 1",
+			want:     "package test\n\nconst c = 1\n\nfunc Run() {}\n",
+		},
+		{
+			name:     "end of file",
+			initial:  withImports,
+			position: "end",
+			content:  "// Last is last.\nfunc Last() {}",
+			want:     "func Run() { fmt.Println() }\n\n// Last is last.\nfunc Last() {}\n",
+		},
+		{
+			name:     "import at end",
+			initial:  withImports,
+			position: "end",
+			content:  `import "os"`,
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "first method",
+			initial:  withImports,
+			position: "first-method-of:Service",
+			content:  "func (s *Service) Init() {}",
+			want:     "type Service struct{}\n\nfunc (s *Service) Init() {}\n\n// Start starts.\n",
+		},
+		{
+			name:     "last method",
+			initial:  withImports,
+			position: "last-method-of:Service",
+			content:  "func (s *Service) Close() {}",
+			want:     "func (s *Service) Stop() {}\n\nfunc (s *Service) Close() {}\n\ntype Empty",
+		},
+		{
+			name:     "type without methods",
+			initial:  withImports,
+			position: "last-method-of:Empty",
+			content:  "func (Empty) String() string { return \"\" }",
+			want:     "type Empty struct{}\n\nfunc (Empty) String() string { return \"\" }\n\nfunc Run()",
+		},
+		{
+			name:     "missing type",
+			initial:  withImports,
+			position: "first-method-of:Servce",
+			content:  "func (s *Servce) Init() {}",
+			wantCode: CodeSymbolNotFound,
+		},
+		{
+			name:     "content syntax",
+			initial:  withImports,
+			position: "end",
+			content:  "func {",
+			wantCode: CodeContentSyntax,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := EditRequest{Path: "test.go", EditType: "insert", Content: tt.content, Insert: &InsertConfig{Position: tt.position}}
+			result := EditSource(req, []byte(tt.initial))
+			if result.Code != tt.wantCode {
+				t.Fatalf("EditSource() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if !strings.Contains(result.Content, tt.want) {
+				t.Errorf("EditSource() content missing %q:\n%s", tt.want, result.Content)
+			}
+			if tt.wantCode == "" && tt.initial == withImports && !strings.Contains(result.Content, "// Package test does things.\npackage test // trailing\n") {
+				t.Errorf("Package clause changed:\n%s", result.Content)
+			}
+		})
+	}
+}
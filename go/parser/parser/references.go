@@ -0,0 +1,386 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReferencesRequest represents a request for the references to a symbol
+type ReferencesRequest struct {
+	Path   string // File declaring the symbol
+	Symbol string // Name, or Type.Member for methods, fields and interface methods
+	Root   string `json:",omitempty"` // Directory whose packages are searched, defaulting to the root of the module containing Path
+}
+
+// ReferencesResult lists the references to a symbol
+type ReferencesResult struct {
+	Success     bool          // Whether the symbol was found
+	Error       string        // Error message if unsuccessful
+	Code        ErrorCode     `json:",omitempty"` // Stable error code if unsuccessful
+	Details     *ErrorDetails `json:",omitempty"` // Structured error details, such as candidate symbols
+	Symbol      string        // Qualified name of the symbol
+	Declaration Reference     // Where the symbol is declared
+	References  []Reference   // Every use of the symbol, in path and offset order
+	Packages    int           // Number of packages type-checked
+	Incomplete  []string      `json:",omitempty"` // Package directories that could not be loaded, whose references are missing
+}
+
+// Reference is one use of a symbol
+type Reference struct {
+	Path      string // File containing the reference
+	Line      int    // 1-based line of the identifier
+	Column    int    // 1-based column of the identifier, in bytes
+	Start     int    // Byte offset of the identifier
+	End       int    // Byte offset of the end of the identifier
+	Enclosing string `json:",omitempty"` // Top-level declaration containing the reference, such as Service.Process
+	Kind      string // "read", "write" or "call"; the declaration is "declaration"
+}
+
+// References type-checks the packages under the module root, with their
+// tests, and returns every reference to a symbol. Only packages that can
+// refer to the symbol are checked: its own for unexported symbols, and
+// those importing it for exported package-level ones.
+func References(req ReferencesRequest) ReferencesResult {
+	if req.Path == "" || req.Symbol == "" {
+		return referencesFailure(newError(CodeInvalidRequest, nil, "Path and Symbol are required"))
+	}
+	path, err := filepath.Abs(req.Path)
+	if err == nil {
+		_, err = os.Stat(path)
+	}
+	if err != nil {
+		return referencesFailure(newError(CodeFileNotFound, nil, "Failed to read file: %v", err))
+	}
+
+	dir := filepath.Dir(path)
+	l := newLoader(dir, nil)
+	pkgs, loadErr := l.loadDir(dir, true)
+	if loadErr != nil {
+		return referencesFailure(loadErr)
+	}
+	home, file, homeErr := packageOf(pkgs, path)
+	if homeErr != nil {
+		return referencesFailure(homeErr)
+	}
+	obj, resolveErr := resolveObject(home.pkg, req.Symbol)
+	if resolveErr != nil {
+		if resolveErr.Code == CodeSymbolNotFound {
+			resolveErr = notFoundError(l.fset, file, req.Symbol, kindAll)
+		}
+		return referencesFailure(resolveErr)
+	}
+	key := objectKey(l.fset, obj)
+
+	root := req.Root
+	if root == "" {
+		root = l.root
+	}
+	var dirs []string
+	if obj.Exported() && root != "" {
+		var walkErr *Error
+		if dirs, walkErr = dependentDirs(root, dir, home.path, obj.Parent() == obj.Pkg().Scope()); walkErr != nil {
+			return referencesFailure(walkErr)
+		}
+	}
+
+	result := ReferencesResult{Success: true, Symbol: objectName(obj), References: []Reference{}, Packages: len(pkgs)}
+	for _, p := range pkgs {
+		result.References = append(result.References, p.references(l.fset, key)...)
+	}
+	for _, d := range dirs {
+		found, err := l.loadDir(d, true)
+		if err != nil {
+			logger.Warn("skipping package", "dir", d, "error", err.Message)
+			rel, _ := filepath.Rel(root, d)
+			result.Incomplete = append(result.Incomplete, rel)
+			continue
+		}
+		for _, p := range found {
+			result.References = append(result.References, p.references(l.fset, key)...)
+		}
+		result.Packages += len(found)
+	}
+	sort.Slice(result.References, func(i, j int) bool {
+		a, b := result.References[i], result.References[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Start < b.Start
+	})
+
+	declared := l.fset.Position(obj.Pos())
+	result.Declaration = Reference{
+		Path:      declared.Filename,
+		Line:      declared.Line,
+		Column:    declared.Column,
+		Start:     declared.Offset,
+		End:       declared.Offset + len(obj.Name()),
+		Enclosing: enclosingSymbol(file, obj.Pos()),
+		Kind:      "declaration",
+	}
+	logger.Debug("found references", "symbol", result.Symbol, "references", len(result.References), "packages", result.Packages)
+	return result
+}
+
+// references returns the uses in p of the object identified by key
+func (p *typedPackage) references(fset *token.FileSet, key string) []Reference {
+	var refs []Reference
+	for _, f := range p.files {
+		var kinds map[*ast.Ident]string
+		for _, ident := range fileIdents(f) {
+			if objectKey(fset, p.info.Uses[ident]) != key {
+				continue
+			}
+			if kinds == nil {
+				kinds = useKinds(f, p.info)
+			}
+			kind := kinds[ident]
+			if kind == "" {
+				kind = "read"
+			}
+			pos := fset.Position(ident.Pos())
+			refs = append(refs, Reference{
+				Path:      pos.Filename,
+				Line:      pos.Line,
+				Column:    pos.Column,
+				Start:     pos.Offset,
+				End:       pos.Offset + len(ident.Name),
+				Enclosing: enclosingSymbol(f, ident.Pos()),
+				Kind:      kind,
+			})
+		}
+	}
+	return refs
+}
+
+// fileIdents returns the identifiers of f
+func fileIdents(f *ast.File) []*ast.Ident {
+	var idents []*ast.Ident
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			idents = append(idents, ident)
+		}
+		return true
+	})
+	return idents
+}
+
+// objectKey identifies obj by the position of its declaration, so that the
+// same declaration matches across the variants of a package checked with and
+// without its tests
+func objectKey(fset *token.FileSet, obj types.Object) string {
+	switch o := obj.(type) {
+	case nil:
+		return ""
+	case *types.Var:
+		obj = o.Origin()
+	case *types.Func:
+		obj = o.Origin()
+	}
+	if !obj.Pos().IsValid() {
+		return ""
+	}
+	pos := fset.Position(obj.Pos())
+	return fmt.Sprintf("%s:%d:%s", pos.Filename, pos.Offset, obj.Name())
+}
+
+// useKinds classifies the identifiers of f that are assigned to or called;
+// any other use is a read
+func useKinds(f *ast.File, info *types.Info) map[*ast.Ident]string {
+	kinds := make(map[*ast.Ident]string)
+	mark := func(e ast.Expr, kind string) {
+		if ident := namedBy(e); ident != nil {
+			kinds[ident] = kind
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				mark(lhs, "write")
+			}
+		case *ast.IncDecStmt:
+			mark(n.X, "write")
+		case *ast.RangeStmt:
+			if n.Tok == token.ASSIGN {
+				mark(n.Key, "write")
+				mark(n.Value, "write")
+			}
+		case *ast.KeyValueExpr:
+			// Keys of struct literals set fields; keys of map literals are read
+			if key, ok := n.Key.(*ast.Ident); ok {
+				if v, ok := info.Uses[key].(*types.Var); ok && v.IsField() {
+					kinds[key] = "write"
+				}
+			}
+		case *ast.CallExpr:
+			fun := unparen(n.Fun)
+			// Explicit instantiations name the function inside an index
+			switch index := fun.(type) {
+			case *ast.IndexExpr:
+				fun = index.X
+			case *ast.IndexListExpr:
+				fun = index.X
+			}
+			if ident := namedBy(fun); ident != nil {
+				switch info.Uses[ident].(type) {
+				case *types.TypeName, *types.Builtin, nil:
+					// Conversions read the type
+				default:
+					kinds[ident] = "call"
+				}
+			}
+		}
+		return true
+	})
+	return kinds
+}
+
+// namedBy returns the identifier naming the variable, field or function e
+// denotes, or nil when e is any other expression
+func namedBy(e ast.Expr) *ast.Ident {
+	switch e := unparen(e).(type) {
+	case *ast.Ident:
+		return e
+	case *ast.SelectorExpr:
+		return e.Sel
+	}
+	return nil
+}
+
+// unparen strips the parentheses around e
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+// enclosingSymbol returns the name of the top-level declaration of f
+// containing pos, qualifying methods with their receiver
+func enclosingSymbol(f *ast.File, pos token.Pos) string {
+	for _, decl := range f.Decls {
+		if pos < decl.Pos() || pos >= decl.End() {
+			continue
+		}
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				return receiverType(d.Recv.List[0].Type) + "." + d.Name.Name
+			}
+			return d.Name.Name
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				if pos < spec.Pos() || pos >= spec.End() {
+					continue
+				}
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					return s.Name.Name
+				case *ast.ValueSpec:
+					names := make([]string, len(s.Names))
+					for i, name := range s.Names {
+						names[i] = name.Name
+					}
+					return strings.Join(names, ", ")
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// packageDirs returns the directories under root holding Go files, skipping
+// those the go tool ignores and nested modules
+func packageDirs(root string) ([]string, *Error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if path != root {
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" || name == "node_modules" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		if matches, _ := filepath.Glob(filepath.Join(path, "*.go")); len(matches) > 0 {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, abs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, newError(CodeReadFailed, nil, "Failed to walk %s: %v", root, err)
+	}
+	return dirs, nil
+}
+
+// dependentDirs returns the directories under root, other than dir, whose
+// packages can refer to a symbol of the package at path: those importing it
+// for package-level symbols, and all of them for fields and methods, which
+// values carry across packages
+func dependentDirs(root, dir, path string, packageLevel bool) ([]string, *Error) {
+	dirs, err := packageDirs(root)
+	if err != nil {
+		return nil, err
+	}
+	abs, _ := filepath.Abs(dir)
+	var dependents []string
+	for _, d := range dirs {
+		if d != abs && (!packageLevel || importsPath(d, path)) {
+			dependents = append(dependents, d)
+		}
+	}
+	return dependents, nil
+}
+
+// importsPath reports whether any Go file in dir imports the package at path
+func importsPath(dir, path string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	fset := token.NewFileSet()
+	for _, m := range matches {
+		f, err := parser.ParseFile(fset, m, nil, parser.ImportsOnly)
+		if err != nil {
+			// Let the type check report the file
+			return true
+		}
+		for _, spec := range f.Imports {
+			if p, _ := strconv.Unquote(spec.Path.Value); p == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// referencesFailure converts an error into a failed ReferencesResult
+func referencesFailure(err *Error) ReferencesResult {
+	return ReferencesResult{
+		Success: false,
+		Error:   err.Message,
+		Code:    err.Code,
+		Details: err.Details,
+	}
+}
@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"svc/service.go": `package svc
+
+// Service processes requests.
+type Service struct {
+	Count int
+	name  string
+}
+
+// Process handles one request.
+func (s *Service) Process() error {
+	s.Count++
+	return nil
+}
+
+func New() *Service {
+	s := &Service{Count: 1, name: "svc"}
+	s.Process()
+	return s
+}
+`,
+		"svc/service_test.go": `package svc
+
+func helper() { New().Process() }
+`,
+		"svc/api_test.go": `package svc_test
+
+import "example.com/m/svc"
+
+var run = (*svc.Service).Process
+`,
+		"api/api.go": `package api
+
+import "example.com/m/svc"
+
+func Get() *svc.Service {
+	s := svc.New()
+	s.Count = 2
+	return s
+}
+`,
+		"cmd/main.go": `package main
+
+import "example.com/m/api"
+
+func main() {
+	n := api.Get().Count
+	_ = n
+	api.Get().Process()
+}
+`,
+		"broken/broken.go": "package broken\n\nimport \"example.com/m/svc\"\n\nfunc {\n",
+		"testdata/skip.go": "package skip\n\nimport \"example.com/m/svc\"\n\nvar _ = svc.New\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		symbol         string
+		want           []string // file:line kind enclosing
+		wantIncomplete []string
+	}{
+		{
+			symbol: "Service.Process",
+			want: []string{
+				"api_test.go:5 read run",
+				"cmd/main.go:8 call main",
+				"service.go:17 call New",
+				"service_test.go:3 call helper",
+			},
+			wantIncomplete: []string{"broken"},
+		},
+		{
+			symbol: "Service.Count",
+			want: []string{
+				"api/api.go:7 write Get",
+				"cmd/main.go:6 read main",
+				"service.go:11 write Service.Process",
+				"service.go:16 write New",
+			},
+			wantIncomplete: []string{"broken"},
+		},
+		{
+			symbol: "Service.name",
+			want:   []string{"service.go:16 write New"},
+		},
+		{
+			symbol: "New",
+			want: []string{
+				"api/api.go:6 call Get",
+				"service_test.go:3 call helper",
+			},
+			wantIncomplete: []string{"broken"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			result := References(ReferencesRequest{Path: filepath.Join(root, "svc", "service.go"), Symbol: tt.symbol})
+			if !result.Success {
+				t.Fatalf("References() error = %s", result.Error)
+			}
+			var got []string
+			for _, ref := range result.References {
+				rel, _ := filepath.Rel(root, ref.Path)
+				if filepath.Dir(rel) == "svc" {
+					rel = filepath.Base(rel)
+				}
+				got = append(got, fmt.Sprintf("%s:%d %s %s", filepath.ToSlash(rel), ref.Line, ref.Kind, ref.Enclosing))
+			}
+			if !sameElements(got, tt.want) {
+				t.Errorf("References() =\n%v\nwant:\n%v", got, tt.want)
+			}
+			if !reflect.DeepEqual(result.Incomplete, tt.wantIncomplete) {
+				t.Errorf("References() incomplete = %v, want %v", result.Incomplete, tt.wantIncomplete)
+			}
+			if result.Declaration.Kind != "declaration" || filepath.Base(result.Declaration.Path) != "service.go" {
+				t.Errorf("References() declaration = %+v", result.Declaration)
+			}
+		})
+	}
+}
+
+func TestReferencesErrors(t *testing.T) {
+	dir := writePackage(t, map[string]string{"a.go": "package a\n\nfunc A() {}\n"})
+	tests := []struct {
+		name     string
+		req      ReferencesRequest
+		wantCode ErrorCode
+	}{
+		{"missing symbol", ReferencesRequest{Path: filepath.Join(dir, "a.go"), Symbol: "B"}, CodeSymbolNotFound},
+		{"missing file", ReferencesRequest{Path: filepath.Join(dir, "b.go"), Symbol: "A"}, CodeFileNotFound},
+		{"no symbol", ReferencesRequest{Path: filepath.Join(dir, "a.go")}, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := References(tt.req); result.Code != tt.wantCode {
+				t.Errorf("References() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+		})
+	}
+}
+
+// sameElements reports whether a and b hold the same strings in any order
+func sameElements(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int)
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		counts[s]--
+	}
+	for _, n := range counts {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
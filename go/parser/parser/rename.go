@@ -0,0 +1,528 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RenameRequest represents a request to rename a symbol
+type RenameRequest struct {
+	Path           string // File declaring the symbol
+	Symbol         string // Name of the symbol, or Type.Member for methods and fields
+	NewName        string // New identifier for the symbol
+	AllowGenerated bool   `json:",omitempty"` // Permit rewriting references in generated files
+}
+
+// RenameResult contains the files changed by a rename
+type RenameResult struct {
+	Success    bool          // Whether the rename was performed
+	Error      string        // Error message if unsuccessful
+	Code       ErrorCode     `json:",omitempty"` // Stable error code if unsuccessful
+	Details    *ErrorDetails `json:",omitempty"` // Structured error details, such as candidate symbols
+	Symbol     string        // Qualified name of the symbol before the rename
+	Renamed    []string      `json:",omitempty"` // Other methods renamed with it so that types keep satisfying interfaces, by their old names
+	References int           // Number of identifiers rewritten, declarations included
+	Files      []FileChange  // Changed files, in path order
+}
+
+// Rename renames a symbol declared in a file and updates every reference to
+// it in the package of the file and its tests, and for exported symbols in
+// the other packages of the module. A method is renamed together with the
+// interface methods it satisfies and their other implementations. Renames
+// that would collide with or be shadowed by another name are refused, and
+// the packages are type-checked again before anything is written.
+func Rename(req RenameRequest) RenameResult {
+	if req.Path == "" || req.Symbol == "" || req.NewName == "" {
+		return renameFailure(newError(CodeInvalidRequest, nil, "Path, Symbol and NewName are required"))
+	}
+	if !token.IsIdentifier(req.NewName) {
+		return renameFailure(newError(CodeInvalidRequest, nil, "Invalid NewName: %q is not a Go identifier", req.NewName))
+	}
+	path, err := filepath.Abs(req.Path)
+	if err == nil {
+		_, err = os.Stat(path)
+	}
+	if err != nil {
+		return renameFailure(newError(CodeFileNotFound, nil, "Failed to read file: %v", err))
+	}
+
+	dir := filepath.Dir(path)
+	l := newLoader(dir, nil)
+	pkgs, loadErr := l.loadDir(dir, true)
+	if loadErr != nil {
+		return renameFailure(loadErr)
+	}
+	home, file, homeErr := packageOf(pkgs, path)
+	if homeErr != nil {
+		return renameFailure(homeErr)
+	}
+
+	obj, renameErr := lookupObject(home.pkg, req.Symbol, req.NewName)
+	if renameErr != nil && renameErr.Code == CodeSymbolNotFound {
+		renameErr = notFoundError(l.fset, file, req.Symbol, kindAll)
+	}
+	if renameErr != nil {
+		return renameFailure(renameErr)
+	}
+	if v, ok := obj.(*types.Var); ok && v.Embedded() {
+		return renameFailure(newError(CodeInvalidRequest, nil, "Cannot rename embedded field %s: it is named after its type, so rename the type instead", req.Symbol))
+	}
+	targets, renameErr := renameTargets(pkgs, obj)
+	if renameErr != nil {
+		return renameFailure(renameErr)
+	}
+
+	// Exported symbols are renamed in the other packages of the module that
+	// can refer to them. Those see the package as checked without its tests,
+	// so targets are matched by the position of their declarations.
+	all, dirs := pkgs, []string{dir}
+	if obj.Exported() && l.root != "" {
+		others, walkErr := dependentDirs(l.root, dir, home.path, obj.Parent() == obj.Pkg().Scope())
+		if walkErr != nil {
+			return renameFailure(walkErr)
+		}
+		for _, d := range others {
+			found, loadErr := l.loadDir(d, true)
+			if loadErr != nil {
+				loadErr.Message = "Cannot rename " + req.Symbol + " in every package using it: " + loadErr.Message
+				return renameFailure(loadErr)
+			}
+			if len(found) > 0 {
+				all, dirs = append(all, found...), append(dirs, d)
+			}
+		}
+	}
+	keys := make(map[string]bool)
+	for o := range targets {
+		keys[objectKey(l.fset, o)] = true
+	}
+	isTarget := func(o types.Object) bool { return targets[o] || keys[objectKey(l.fset, o)] }
+	if renameErr := renameConflict(all, isTarget, obj, req.NewName); renameErr != nil {
+		return renameFailure(renameErr)
+	}
+
+	// Collect the offsets of the declarations and uses in each file, and of
+	// the names opening their doc comments
+	offsets := make(map[string][]int)
+	seen := make(map[token.Pos]bool)
+	for _, p := range all {
+		for _, idents := range []map[*ast.Ident]types.Object{p.info.Defs, p.info.Uses} {
+			for ident, o := range idents {
+				if isTarget(o) && !seen[ident.Pos()] {
+					seen[ident.Pos()] = true
+					pos := l.fset.Position(ident.Pos())
+					offsets[pos.Filename] = append(offsets[pos.Filename], pos.Offset)
+				}
+			}
+		}
+	}
+	for _, p := range pkgs {
+		for i, f := range p.files {
+			for o := range targets {
+				if o.Pos() < f.FileStart || o.Pos() >= f.FileEnd {
+					continue
+				}
+				if doc := docComment(f, o); doc != nil {
+					text := doc.List[0].Text
+					if prefix := "// " + o.Name(); strings.HasPrefix(text, prefix+" ") || text == prefix {
+						offsets[p.names[i]] = append(offsets[p.names[i]], l.fset.Position(doc.List[0].Slash).Offset+3)
+					}
+				}
+			}
+		}
+	}
+
+	var writes []fileWrite
+	overlay := make(map[string][]byte)
+	for _, p := range all {
+		for i, f := range p.files {
+			name := p.names[i]
+			if len(offsets[name]) == 0 {
+				continue
+			}
+			if generator, generated := generatedBy(f); generated && !req.AllowGenerated {
+				genErr := generatedFileError(generator)
+				genErr.Message = name + ": " + genErr.Message
+				return renameFailure(genErr)
+			}
+			edited, err := replaceIdents(p.content[i], offsets[name], obj.Name(), req.NewName)
+			if err != nil {
+				return renameFailure(newError(CodeFormatFailed, nil, "Failed to format renamed code: %v", err))
+			}
+			writes = append(writes, fileWrite{path: name, before: p.content[i], after: edited})
+			overlay[name] = edited
+		}
+	}
+	sort.Slice(writes, func(i, j int) bool { return writes[i].path < writes[j].path })
+
+	// Anything the checks above missed shows up as a new type error
+	if checkErr := recheckRename(l.withOverlay(overlay), dirs, all, obj.Name(), req.NewName); checkErr != nil {
+		return renameFailure(checkErr)
+	}
+	if commitErr := commitFiles(writes); commitErr != nil {
+		return renameFailure(commitErr)
+	}
+
+	result := RenameResult{Success: true, Symbol: objectName(obj), References: len(seen)}
+	for o := range targets {
+		if _, ok := o.(*types.Func); ok && o != obj {
+			result.Renamed = append(result.Renamed, objectName(o))
+		}
+	}
+	sort.Strings(result.Renamed)
+	for _, w := range writes {
+		result.Files = append(result.Files, w.change())
+	}
+	logger.Debug("renamed symbol", "path", req.Path, "symbol", req.Symbol, "newName", req.NewName, "references", result.References, "files", len(writes))
+	return result
+}
+
+// renameTargets returns the objects renamed along with obj: the embedded
+// fields named after a type, and for a method, the interface methods it
+// satisfies and their other implementations in the package
+func renameTargets(pkgs []*typedPackage, obj types.Object) (map[types.Object]bool, *Error) {
+	targets := map[types.Object]bool{obj: true}
+	local := make(map[*types.Package]bool)
+	var ifaces, concretes []*types.TypeName
+	for _, p := range pkgs {
+		local[p.pkg] = true
+		for _, name := range p.pkg.Scope().Names() {
+			tn, ok := p.pkg.Scope().Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			// Generic types only implement interfaces once instantiated
+			if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				continue
+			}
+			if types.IsInterface(tn.Type()) {
+				ifaces = append(ifaces, tn)
+			} else {
+				concretes = append(concretes, tn)
+			}
+		}
+	}
+
+	if tn, ok := obj.(*types.TypeName); ok {
+		for _, p := range pkgs {
+			for _, o := range p.info.Defs {
+				if v, ok := o.(*types.Var); ok && v.Embedded() && embeddedType(v.Type()) == tn {
+					targets[v] = true
+				}
+			}
+		}
+		return targets, nil
+	}
+	if _, ok := obj.(*types.Func); !ok {
+		return targets, nil
+	}
+
+	// Renaming either side of an implementation renames the other, until
+	// no more methods are drawn in
+	for changed := true; changed; {
+		changed = false
+		for _, iface := range ifaces {
+			it := iface.Type().Underlying().(*types.Interface)
+			var im *types.Func
+			for i := 0; i < it.NumMethods(); i++ {
+				if it.Method(i).Name() == obj.Name() {
+					im = it.Method(i)
+				}
+			}
+			if im == nil {
+				continue
+			}
+			for _, c := range concretes {
+				if !types.Implements(c.Type(), it) && !types.Implements(types.NewPointer(c.Type()), it) {
+					continue
+				}
+				found, _, _ := types.LookupFieldOrMethod(c.Type(), true, c.Pkg(), obj.Name())
+				cm, ok := found.(*types.Func)
+				if !ok || targets[im] == targets[cm] {
+					continue
+				}
+				for _, m := range []*types.Func{im, cm} {
+					if targets[m] {
+						continue
+					}
+					if !local[m.Pkg()] {
+						return nil, newError(CodeNameConflict, &ErrorDetails{Symbol: objectName(m)},
+							"Cannot rename %s: %s would have to be renamed with it, but it is declared in package %s", objectName(obj), objectName(m), m.Pkg().Path())
+					}
+					targets[m] = true
+					changed = true
+				}
+			}
+		}
+	}
+	return targets, nil
+}
+
+// renameConflict reports a rename that would be shadowed by, or capture the
+// references of, another declaration, or hide a symbol from the packages
+// that use it
+func renameConflict(pkgs []*typedPackage, isTarget func(types.Object) bool, obj types.Object, newName string) *Error {
+	conflict := func(format string, args ...interface{}) *Error {
+		return newError(CodeNameConflict, &ErrorDetails{Symbol: newName}, "Cannot rename %s to %s: "+format, append([]interface{}{objectName(obj), newName}, args...)...)
+	}
+	packageLevel := obj.Parent() != nil && obj.Parent() == obj.Pkg().Scope()
+
+	for _, p := range pkgs {
+		for ident, o := range p.info.Uses {
+			switch {
+			case isTarget(o) && o.Pkg() != p.pkg && !token.IsExported(newName):
+				return conflict("it is used by package %s, which could no longer refer to it", p.pkg.Name())
+			case isTarget(o) && packageLevel && o.Pkg() == p.pkg:
+				scope := p.pkg.Scope().Innermost(ident.Pos())
+				if scope == nil {
+					continue
+				}
+				if _, found := scope.LookupParent(newName, ident.Pos()); found != nil && found.Parent() != types.Universe {
+					return conflict("the reference at %s would refer to the %s declared at %s",
+						p.position(ident.Pos()), objectKind(found), p.position(found.Pos()))
+				}
+			case o.Parent() == types.Universe && o.Name() == newName && packageLevel && p.pkg == obj.Pkg():
+				return conflict("the predeclared %s used at %s would refer to the renamed symbol", newName, p.position(ident.Pos()))
+			}
+		}
+		if !packageLevel || p.pkg != obj.Pkg() {
+			continue
+		}
+		for _, f := range p.files {
+			if scope := p.info.Scopes[f]; scope != nil {
+				if found := scope.Lookup(newName); found != nil {
+					return conflict("%s imports a package as %s", p.position(f.Package), newName)
+				}
+			}
+		}
+	}
+
+	if packageLevel {
+		return nil
+	}
+	// Fields and methods must stay unique in every type that has them,
+	// including those that embed their declaring type
+	for _, p := range pkgs {
+		for _, name := range p.pkg.Scope().Names() {
+			tn, ok := p.pkg.Scope().Lookup(name).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			for _, t := range []types.Type{tn.Type(), types.NewPointer(tn.Type())} {
+				old, _, _ := types.LookupFieldOrMethod(t, true, p.pkg, obj.Name())
+				if !isTarget(old) {
+					continue
+				}
+				if existing, _, _ := types.LookupFieldOrMethod(t, true, p.pkg, newName); existing != nil && !isTarget(existing) {
+					return conflict("%s already has a field or method %s", tn.Name(), newName)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// recheckRename type-checks the packages in dirs with l, which reads the
+// renamed files, and fails if the rename introduced type errors
+func recheckRename(l *loader, dirs []string, before []*typedPackage, oldName, newName string) *Error {
+	var after []*typedPackage
+	for _, dir := range dirs {
+		pkgs, loadErr := l.loadDir(dir, true)
+		if loadErr != nil {
+			return loadErr
+		}
+		after = append(after, pkgs...)
+	}
+	rewrite := func(msg string) string { return strings.ReplaceAll(msg, oldName, newName) }
+	if err := addedErrors(before, after, rewrite); err != nil {
+		return newError(CodeNameConflict, &ErrorDetails{Symbol: newName}, "Cannot rename %s to %s: %v", oldName, newName, err)
+	}
+	return nil
+}
+
+// position describes pos as file:line relative to the package directory
+func (p *typedPackage) position(pos token.Pos) string {
+	for i, f := range p.files {
+		if pos >= f.FileStart && pos <= f.FileEnd {
+			line := 1 + strings.Count(string(p.content[i][:min(int(pos-f.FileStart), len(p.content[i]))]), "\n")
+			return fmt.Sprintf("%s:%d", filepath.Base(p.names[i]), line)
+		}
+	}
+	return "another package"
+}
+
+// embeddedType returns the declaration of the type an embedded field is
+// named after
+func embeddedType(t types.Type) *types.TypeName {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Origin().Obj()
+	}
+	return nil
+}
+
+// objectName returns the name of obj, qualified by its receiver for methods
+func objectName(obj types.Object) string {
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			t := recv.Type()
+			if ptr, ok := t.(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+			if named, ok := t.(*types.Named); ok {
+				return named.Obj().Name() + "." + fn.Name()
+			}
+		}
+	}
+	return obj.Name()
+}
+
+// objectKind describes the kind of a declaration for error messages
+func objectKind(obj types.Object) string {
+	switch o := obj.(type) {
+	case *types.PkgName:
+		return "import"
+	case *types.TypeName:
+		return "type"
+	case *types.Const:
+		return "constant"
+	case *types.Func:
+		return "function"
+	case *types.Var:
+		if o.IsField() {
+			return "field"
+		}
+		return "variable"
+	}
+	return "declaration"
+}
+
+// renameFailure converts an error into a failed RenameResult
+func renameFailure(err *Error) RenameResult {
+	return RenameResult{
+		Success: false,
+		Error:   err.Message,
+		Code:    err.Code,
+		Details: err.Details,
+	}
+}
+
+// lookupObject resolves a package-level symbol or Type.Member selector and
+// checks that newName does not collide with an existing name
+func lookupObject(pkg *types.Package, symbol, newName string) (types.Object, *Error) {
+	obj, err := resolveObject(pkg, symbol)
+	if err != nil {
+		return nil, err
+	}
+	typeName, _, qualified := strings.Cut(symbol, ".")
+	if !qualified {
+		if pkg.Scope().Lookup(newName) != nil {
+			return nil, newError(CodeNameConflict, &ErrorDetails{Symbol: newName}, "Cannot rename %s: %s is already declared in package %s", symbol, newName, pkg.Name())
+		}
+		return obj, nil
+	}
+	tn := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if existing, _, _ := types.LookupFieldOrMethod(tn.Type(), true, pkg, newName); existing != nil {
+		return nil, newError(CodeNameConflict, &ErrorDetails{Symbol: typeName + "." + newName}, "Cannot rename %s: %s already has a field or method %s", symbol, typeName, newName)
+	}
+	return obj, nil
+}
+
+// resolveObject resolves a package-level symbol or Type.Member selector to
+// its object
+func resolveObject(pkg *types.Package, symbol string) (types.Object, *Error) {
+	notFound := newError(CodeSymbolNotFound, &ErrorDetails{Symbol: symbol}, "Symbol not found: %s", symbol)
+	if pkg == nil {
+		return nil, notFound
+	}
+
+	typeName, member, qualified := strings.Cut(symbol, ".")
+	if !qualified {
+		obj := pkg.Scope().Lookup(symbol)
+		if obj == nil {
+			return nil, notFound
+		}
+		return obj, nil
+	}
+
+	tn, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, notFound
+	}
+	obj, _, _ := types.LookupFieldOrMethod(tn.Type(), true, pkg, member)
+	if obj == nil {
+		return nil, notFound
+	}
+	return obj, nil
+}
+
+// docComment returns the doc comment of the declaration of obj in file
+func docComment(file *ast.File, obj types.Object) *ast.CommentGroup {
+	var doc *ast.CommentGroup
+	ast.Inspect(file, func(n ast.Node) bool {
+		if doc != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Name.Pos() == obj.Pos() {
+				doc = n.Doc
+			}
+		case *ast.GenDecl:
+			for _, spec := range n.Specs {
+				var names []*ast.Ident
+				var specDoc *ast.CommentGroup
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names, specDoc = []*ast.Ident{s.Name}, s.Doc
+				case *ast.ValueSpec:
+					names, specDoc = s.Names, s.Doc
+				}
+				for _, name := range names {
+					if name.Pos() == obj.Pos() {
+						doc = specDoc
+						if doc == nil && len(n.Specs) == 1 {
+							doc = n.Doc
+						}
+					}
+				}
+			}
+		case *ast.Field:
+			for _, name := range n.Names {
+				if name.Pos() == obj.Pos() {
+					doc = n.Doc
+				}
+			}
+		}
+		return true
+	})
+	return doc
+}
+
+// replaceIdents replaces the identifier oldName at each offset with newName
+// and formats the result
+func replaceIdents(content []byte, offsets []int, oldName, newName string) ([]byte, error) {
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	edited := append([]byte(nil), content...)
+	last := -1
+	for _, offset := range offsets {
+		if offset == last {
+			continue
+		}
+		last = offset
+		if string(edited[offset:offset+len(oldName)]) != oldName {
+			return nil, fmt.Errorf("unexpected identifier at offset %d", offset)
+		}
+		edited = append(edited[:offset], append([]byte(newName), edited[offset+len(oldName):]...)...)
+	}
+	return format.Source(edited)
+}
@@ -0,0 +1,386 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRename(t *testing.T) {
+	initial := `package test
+
+// Service processes requests
+type Service struct {
+	Name string
+}
+
+func (s *Service) Process() error {
+	if s.Name == "" {
+		return s.validate()
+	}
+	return nil
+}
+
+func (s *Service) validate() error { return nil }
+
+func New() *Service {
+	Name := "shadow"
+	return &Service{Name: Name}
+}
+`
+
+	tests := []struct {
+		name     string
+		req      RenameRequest
+		wantCode ErrorCode
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:    "type",
+			req:     RenameRequest{Symbol: "Service", NewName: "Worker"},
+			want:    []string{"// Worker processes requests", "type Worker struct", "func (s *Worker) Process()", "func New() *Worker", "&Worker{Name: Name}"},
+			notWant: []string{"Service"},
+		},
+		{
+			name:    "field",
+			req:     RenameRequest{Symbol: "Service.Name", NewName: "Label"},
+			want:    []string{"\tLabel string", "if s.Label == \"\"", "&Service{Label: Name}", `Name := "shadow"`},
+			notWant: []string{"s.Name"},
+		},
+		{
+			name: "method",
+			req:  RenameRequest{Symbol: "Service.validate", NewName: "check"},
+			want: []string{"func (s *Service) check() error", "return s.check()"},
+		},
+		{
+			name:     "package conflict",
+			req:      RenameRequest{Symbol: "New", NewName: "Service"},
+			wantCode: CodeNameConflict,
+		},
+		{
+			name:     "member conflict",
+			req:      RenameRequest{Symbol: "Service.Name", NewName: "Process"},
+			wantCode: CodeNameConflict,
+		},
+		{
+			name:     "symbol not found",
+			req:      RenameRequest{Symbol: "Missing", NewName: "Other"},
+			wantCode: CodeSymbolNotFound,
+		},
+		{
+			name:     "invalid name",
+			req:      RenameRequest{Symbol: "New", NewName: "1st"},
+			wantCode: CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.go")
+			if err := os.WriteFile(path, []byte(initial), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tt.req.Path = path
+
+			result := Rename(tt.req)
+			if result.Code != tt.wantCode {
+				t.Fatalf("Rename() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != "" && string(content) != initial {
+				t.Error("File was modified by a failed rename")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("Renamed file missing %q:\n%s", want, content)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(content), notWant) {
+					t.Errorf("Renamed file still contains %q:\n%s", notWant, content)
+				}
+			}
+		})
+	}
+}
+
+func TestRenamePackage(t *testing.T) {
+	files := map[string]string{
+		"store.go": `package store
+
+// Getter reads values.
+type Getter interface {
+	Get(key string) string
+}
+
+// Store keeps values in memory.
+type Store struct {
+	data map[string]string
+}
+
+// Get returns the value of key.
+func (s *Store) Get(key string) string { return s.data[key] }
+
+// Cached wraps a Store.
+type Cached struct {
+	*Store
+}
+
+var Default = &Store{}
+`,
+		"use.go": `package store
+
+func lookup(g Getter, key string) string {
+	c := Cached{Store: Default}
+	return g.Get(key) + c.Store.Get(key) + Default.Get(key)
+}
+
+func count(items []string) int {
+	size := len(items)
+	return size
+}
+`,
+		"store_test.go": `package store
+
+func helper() string { return Default.Get("k") }
+`,
+		"api_test.go": `package store_test
+
+import "example.com/store"
+
+func use() string { return store.Default.Get("k") + new(store.Store).Get("x") }
+`,
+	}
+
+	tests := []struct {
+		name        string
+		req         RenameRequest
+		wantCode    ErrorCode
+		wantRenamed []string
+		wantFiles   []string
+		want        map[string][]string // substrings of each file afterwards
+	}{
+		{
+			name:        "interface method with implementations",
+			req:         RenameRequest{Symbol: "Getter.Get", NewName: "Fetch"},
+			wantRenamed: []string{"Store.Get"},
+			wantFiles:   []string{"api_test.go", "store.go", "store_test.go", "use.go"},
+			want: map[string][]string{
+				"store.go":      {"Fetch(key string) string", "// Fetch returns the value of key.", "func (s *Store) Fetch(key string)"},
+				"use.go":        {"g.Fetch(key) + c.Store.Fetch(key) + Default.Fetch(key)"},
+				"store_test.go": {`Default.Fetch("k")`},
+				"api_test.go":   {`store.Default.Fetch("k") + new(store.Store).Fetch("x")`},
+			},
+		},
+		{
+			name:        "method drags its interface along",
+			req:         RenameRequest{Symbol: "Store.Get", NewName: "Lookup"},
+			wantRenamed: []string{"Getter.Get"},
+			wantFiles:   []string{"api_test.go", "store.go", "store_test.go", "use.go"},
+			want:        map[string][]string{"store.go": {"\tLookup(key string) string", "func (s *Store) Lookup("}},
+		},
+		{
+			name:      "type and embedded fields",
+			req:       RenameRequest{Symbol: "Store", NewName: "Memory"},
+			wantFiles: []string{"api_test.go", "store.go", "use.go"},
+			want: map[string][]string{
+				"store.go":    {"// Memory keeps values in memory.", "\t*Memory\n", "var Default = &Memory{}"},
+				"use.go":      {"Cached{Memory: Default}", "c.Memory.Get(key)"},
+				"api_test.go": {"new(store.Memory)"},
+			},
+		},
+		{
+			name:      "variable used by tests",
+			req:       RenameRequest{Symbol: "Default", NewName: "Shared"},
+			wantFiles: []string{"api_test.go", "store.go", "store_test.go", "use.go"},
+		},
+		{
+			name:     "unexported name used by external tests",
+			req:      RenameRequest{Symbol: "Default", NewName: "shared"},
+			wantCode: CodeNameConflict,
+		},
+		{
+			name:     "shadowed by a local",
+			req:      RenameRequest{Symbol: "Default", NewName: "c"},
+			wantCode: CodeNameConflict,
+		},
+		{
+			name:     "captures a builtin",
+			req:      RenameRequest{Symbol: "lookup", NewName: "len"},
+			wantCode: CodeNameConflict,
+		},
+		{
+			name:     "collides with a promoted method",
+			req:      RenameRequest{Symbol: "Store.Get", NewName: "data"},
+			wantCode: CodeNameConflict,
+		},
+		{
+			name:     "embedded field",
+			req:      RenameRequest{Symbol: "Cached.Store", NewName: "Inner"},
+			wantCode: CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writePackage(t, files)
+			tt.req.Path = filepath.Join(dir, "store.go")
+			result := Rename(tt.req)
+			if result.Code != tt.wantCode {
+				t.Fatalf("Rename() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if tt.wantCode != "" {
+				for name, content := range files {
+					if got, _ := os.ReadFile(filepath.Join(dir, name)); string(got) != content {
+						t.Errorf("%s was modified by a failed rename", name)
+					}
+				}
+				return
+			}
+			if !reflect.DeepEqual(result.Renamed, tt.wantRenamed) {
+				t.Errorf("Rename() renamed = %v, want %v", result.Renamed, tt.wantRenamed)
+			}
+			var changed []string
+			for _, f := range result.Files {
+				changed = append(changed, filepath.Base(f.Path))
+				if f.Diff == "" {
+					t.Errorf("No diff for %s", f.Path)
+				}
+			}
+			if !reflect.DeepEqual(changed, tt.wantFiles) {
+				t.Errorf("Rename() files = %v, want %v", changed, tt.wantFiles)
+			}
+			for name, wants := range tt.want {
+				content, _ := os.ReadFile(filepath.Join(dir, name))
+				for _, want := range wants {
+					if !strings.Contains(string(content), want) {
+						t.Errorf("%s missing %q:\n%s", name, want, content)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenameForeignInterface(t *testing.T) {
+	dir := writePackage(t, map[string]string{"r.go": `package r
+
+import "io"
+
+type Source interface{ io.Reader }
+
+type File struct{}
+
+func (File) Read(p []byte) (int, error) { return 0, nil }
+`})
+	result := Rename(RenameRequest{Path: filepath.Join(dir, "r.go"), Symbol: "File.Read", NewName: "Load"})
+	if result.Code != CodeNameConflict {
+		t.Errorf("Rename() code = %q, want %q (error: %s)", result.Code, CodeNameConflict, result.Error)
+	}
+}
+
+func TestRenameModule(t *testing.T) {
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"a/a.go": `package a
+
+// Hello greets.
+func Hello() string { return "hello" }
+
+type T struct{}
+
+func (T) Name() string { return "t" }
+`,
+		"b/b.go": `package b
+
+import "example.com/m/a"
+
+var greeting = a.Hello()
+
+type Wrapper struct{ a.T }
+
+func (Wrapper) Label() string { return "w" }
+
+func Describe(w Wrapper) string { return w.Name() }
+`,
+		"c/c.go": "package c\n\nfunc Hello() {}\n",
+	}
+
+	tests := []struct {
+		name      string
+		req       RenameRequest
+		wantCode  ErrorCode
+		wantFiles []string
+		want      map[string]string
+	}{
+		{
+			name:      "function used by an importer",
+			req:       RenameRequest{Symbol: "Hello", NewName: "Greet"},
+			wantFiles: []string{"a/a.go", "b/b.go"},
+			want:      map[string]string{"a/a.go": "// Greet greets.\nfunc Greet()", "b/b.go": "a.Greet()"},
+		},
+		{
+			name:      "method called through an embedding type",
+			req:       RenameRequest{Symbol: "T.Name", NewName: "Title"},
+			wantFiles: []string{"a/a.go", "b/b.go"},
+			want:      map[string]string{"a/a.go": "func (T) Title()", "b/b.go": "w.Title()"},
+		},
+		{
+			name:     "unexported name used by an importer",
+			req:      RenameRequest{Symbol: "Hello", NewName: "hello"},
+			wantCode: CodeNameConflict,
+		},
+		{
+			name:     "method colliding in an embedding type",
+			req:      RenameRequest{Symbol: "T.Name", NewName: "Label"},
+			wantCode: CodeNameConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.req.Path = filepath.Join(root, "a", "a.go")
+			result := Rename(tt.req)
+			if result.Code != tt.wantCode {
+				t.Fatalf("Rename() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if tt.wantCode != "" {
+				for name, content := range files {
+					if got, _ := os.ReadFile(filepath.Join(root, name)); string(got) != content {
+						t.Errorf("%s was modified by a failed rename", name)
+					}
+				}
+				return
+			}
+			var changed []string
+			for _, f := range result.Files {
+				rel, _ := filepath.Rel(root, f.Path)
+				changed = append(changed, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(changed, tt.wantFiles) {
+				t.Errorf("Rename() files = %v, want %v", changed, tt.wantFiles)
+			}
+			for name, want := range tt.want {
+				if content, _ := os.ReadFile(filepath.Join(root, name)); !strings.Contains(string(content), want) {
+					t.Errorf("%s missing %q:\n%s", name, want, content)
+				}
+			}
+		})
+	}
+}
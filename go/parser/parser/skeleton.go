@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// elidedBody replaces function bodies in a skeleton
+const elidedBody = "{ ... }"
+
+// SkeletonRequest represents a request for the outline of a file as source
+type SkeletonRequest struct {
+	Path string   // File to summarise
+	Keep []string `json:",omitempty"` // Functions, methods or types whose bodies are kept in full; a type keeps all its methods
+}
+
+// SkeletonResult contains a file with its function bodies elided
+type SkeletonResult struct {
+	Success bool          // Whether the skeleton was built
+	Error   string        // Error message if unsuccessful
+	Code    ErrorCode     `json:",omitempty"` // Stable error code if unsuccessful
+	Details *ErrorDetails `json:",omitempty"` // Structured error details, such as candidate symbols
+	Content string        // The file with bodies replaced by "{ ... }"
+	LineMap []int         // LineMap[i] is the 1-based line of the original file where line i+1 of Content starts
+}
+
+// Skeleton returns a file with every function and method body replaced by
+// "{ ... }", except those of the symbols named in Keep
+func Skeleton(req SkeletonRequest) SkeletonResult {
+	if req.Path == "" {
+		return skeletonFailure(newError(CodeInvalidRequest, nil, "Path is required"))
+	}
+	content, err := os.ReadFile(req.Path)
+	if err != nil {
+		code := CodeReadFailed
+		if errors.Is(err, fs.ErrNotExist) {
+			code = CodeFileNotFound
+		}
+		return skeletonFailure(newError(code, nil, "Failed to read file: %v", err))
+	}
+	return SkeletonSource(req, content)
+}
+
+// SkeletonSource builds the skeleton of the given file content
+func SkeletonSource(req SkeletonRequest, content []byte) SkeletonResult {
+	fset := token.NewFileSet()
+	file, err := parseFile(fset, req.Path, content)
+	if err != nil {
+		return skeletonFailure(newError(CodeParseFailed, syntaxDetails(err, 0), "Failed to parse file: %v", err))
+	}
+
+	// Resolve the kept symbols up front so that misspellings are reported
+	keep := make(map[ast.Decl]bool)
+	keepTypes := make(map[string]bool)
+	for _, symbol := range req.Keep {
+		sel, selErr := selectSymbol(fset, file, symbol)
+		if selErr != nil {
+			return skeletonFailure(selErr)
+		}
+		switch sel.kind {
+		case "struct", "interface", "type":
+			keepTypes[sel.name] = true
+		}
+		if fn, ok := sel.node.(*ast.FuncDecl); ok {
+			keep[fn] = true
+		}
+	}
+
+	var bodies [][2]int
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || keep[fn] {
+			continue
+		}
+		if fn.Recv != nil && len(fn.Recv.List) > 0 && keepTypes[receiverType(fn.Recv.List[0].Type)] {
+			continue
+		}
+		bodies = append(bodies, [2]int{fset.Position(fn.Body.Lbrace).Offset, fset.Position(fn.Body.Rbrace).Offset + 1})
+	}
+	sort.Slice(bodies, func(i, j int) bool { return bodies[i][0] < bodies[j][0] })
+
+	out, lineMap := elide(content, bodies)
+	logger.Debug("built skeleton", "path", req.Path, "elided", len(bodies), "lines", len(lineMap))
+	return SkeletonResult{
+		Success: true,
+		Content: string(out),
+		LineMap: lineMap,
+	}
+}
+
+// elide replaces each of the sorted byte ranges in content with elidedBody
+// and maps every output line to the original line it starts on
+func elide(content []byte, ranges [][2]int) ([]byte, []int) {
+	var out bytes.Buffer
+	lineMap := []int{1}
+	line := 1
+	copyText := func(text []byte) {
+		for _, b := range text {
+			out.WriteByte(b)
+			if b == '\n' {
+				line++
+				lineMap = append(lineMap, line)
+			}
+		}
+	}
+
+	pos := 0
+	for _, r := range ranges {
+		copyText(content[pos:r[0]])
+		out.WriteString(elidedBody)
+		line += bytes.Count(content[r[0]:r[1]], []byte("\n"))
+		pos = r[1]
+	}
+	copyText(content[pos:])
+
+	// A trailing newline does not start another line
+	if bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		lineMap = lineMap[:len(lineMap)-1]
+	}
+	return out.Bytes(), lineMap
+}
+
+// skeletonFailure converts an error into a failed SkeletonResult
+func skeletonFailure(err *Error) SkeletonResult {
+	return SkeletonResult{
+		Success: false,
+		Error:   err.Message,
+		Code:    err.Code,
+		Details: err.Details,
+	}
+}
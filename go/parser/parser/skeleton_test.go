@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSkeleton(t *testing.T) {
+	content := `package test
+
+// Service processes requests
+type Service struct {
+	Name string
+}
+
+// Process handles data
+func (s *Service) Process() error {
+	// validate first
+	if s.Name == "" {
+		return nil
+	}
+	return nil
+}
+
+func (s *Service) Close() {}
+
+func Helper(n int) int {
+	return n * 2
+}
+
+var handler = func() {
+	println("kept: not a declaration body")
+}
+`
+	path := filepath.Join(t.TempDir(), "test.go")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		keep        []string
+		wantCode    ErrorCode
+		want        []string
+		notWant     []string
+		wantLineMap map[string]int // output line prefix -> original line
+	}{
+		{
+			name: "all bodies elided",
+			want: []string{
+				"// Process handles data\nfunc (s *Service) Process() error { ... }\n",
+				"func (s *Service) Close() { ... }\n",
+				"func Helper(n int) int { ... }\n\nvar handler = func() {\n",
+				"type Service struct {\n\tName string\n}",
+			},
+			notWant: []string{"validate first", "n * 2"},
+			wantLineMap: map[string]int{
+				"func (s *Service) Close()": 17,
+				"func Helper":               19,
+				"var handler":               23,
+				"}":                         6,
+			},
+		},
+		{
+			name:    "keep function",
+			keep:    []string{"Helper"},
+			want:    []string{"func Helper(n int) int {\n\treturn n * 2\n}", "Process() error { ... }"},
+			notWant: []string{"validate first"},
+			wantLineMap: map[string]int{
+				"\treturn n * 2": 20,
+			},
+		},
+		{
+			name:    "keep type keeps methods",
+			keep:    []string{"Service"},
+			want:    []string{"// validate first", "func (s *Service) Close() {}", "func Helper(n int) int { ... }"},
+			notWant: []string{"n * 2"},
+		},
+		{
+			name:     "unknown symbol",
+			keep:     []string{"Missing"},
+			wantCode: CodeSymbolNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Skeleton(SkeletonRequest{Path: path, Keep: tt.keep})
+			if result.Code != tt.wantCode {
+				t.Fatalf("Skeleton() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if tt.wantCode != "" {
+				return
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result.Content, want) {
+					t.Errorf("Skeleton missing %q:\n%s", want, result.Content)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(result.Content, notWant) {
+					t.Errorf("Skeleton still contains %q:\n%s", notWant, result.Content)
+				}
+			}
+
+			lines := strings.Split(strings.TrimSuffix(result.Content, "\n"), "\n")
+			if len(lines) != len(result.LineMap) {
+				t.Fatalf("LineMap has %d entries for %d lines", len(result.LineMap), len(lines))
+			}
+			original := strings.Split(content, "\n")
+			for i, line := range lines {
+				// Lines outside elided bodies are copied verbatim
+				if !strings.Contains(line, elidedBody) && original[result.LineMap[i]-1] != line {
+					t.Errorf("Line %d %q maps to original line %d %q", i+1, line, result.LineMap[i], original[result.LineMap[i]-1])
+				}
+			}
+			for prefix, want := range tt.wantLineMap {
+				found := false
+				for i, line := range lines {
+					if strings.HasPrefix(line, prefix) {
+						found = true
+						if result.LineMap[i] != want {
+							t.Errorf("Line %q maps to %d, want %d", line, result.LineMap[i], want)
+						}
+						break
+					}
+				}
+				if !found {
+					t.Errorf("No line starting with %q", prefix)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"go/ast"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// StatementConfig selects a statement inside the body of the target function
+// for insert-statement, replace-statement and delete-statement operations
+type StatementConfig struct {
+	Anchor   string `json:",omitempty"` // Source snippet contained in the statement; the innermost match is used
+	Path     string `json:",omitempty"` // Statement path such as "3" or "2.1.1", alternating 1-based statement and block indices
+	Position string `json:",omitempty"` // Required for insert-statement: "before" or "after"
+}
+
+// stmtMatch is a statement of a function body together with its path
+type stmtMatch struct {
+	stmt ast.Stmt
+	path string
+}
+
+// stmtBlocks returns the statement lists nested directly in s, in source
+// order: the body and else of an if, each clause of a switch or select, and
+// the bodies of function literals in simple statements
+func stmtBlocks(s ast.Stmt) [][]ast.Stmt {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		return [][]ast.Stmt{s.List}
+	case *ast.LabeledStmt:
+		return stmtBlocks(s.Stmt)
+	case *ast.IfStmt:
+		blocks := [][]ast.Stmt{s.Body.List}
+		switch e := s.Else.(type) {
+		case *ast.BlockStmt:
+			blocks = append(blocks, e.List)
+		case *ast.IfStmt:
+			blocks = append(blocks, []ast.Stmt{e})
+		}
+		return blocks
+	case *ast.ForStmt:
+		return [][]ast.Stmt{s.Body.List}
+	case *ast.RangeStmt:
+		return [][]ast.Stmt{s.Body.List}
+	case *ast.SwitchStmt:
+		return clauseBlocks(s.Body)
+	case *ast.TypeSwitchStmt:
+		return clauseBlocks(s.Body)
+	case *ast.SelectStmt:
+		return clauseBlocks(s.Body)
+	}
+	var blocks [][]ast.Stmt
+	ast.Inspect(s, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok {
+			blocks = append(blocks, lit.Body.List)
+			return false
+		}
+		return true
+	})
+	return blocks
+}
+
+// clauseBlocks returns the statements of each case of a switch or select
+func clauseBlocks(body *ast.BlockStmt) [][]ast.Stmt {
+	var blocks [][]ast.Stmt
+	for _, clause := range body.List {
+		switch c := clause.(type) {
+		case *ast.CaseClause:
+			blocks = append(blocks, c.Body)
+		case *ast.CommClause:
+			blocks = append(blocks, c.Body)
+		}
+	}
+	return blocks
+}
+
+// walkStmts calls fn for every statement in list and the blocks nested in it,
+// parents before their children
+func walkStmts(list []ast.Stmt, prefix string, fn func(stmtMatch)) {
+	for i, s := range list {
+		path := prefix + strconv.Itoa(i+1)
+		fn(stmtMatch{stmt: s, path: path})
+		for j, block := range stmtBlocks(s) {
+			walkStmts(block, path+"."+strconv.Itoa(j+1)+".", fn)
+		}
+	}
+}
+
+// stmtKind names the kind of a statement for error candidates
+func stmtKind(s ast.Stmt) string {
+	switch s := s.(type) {
+	case *ast.AssignStmt:
+		if s.Tok == token.DEFINE {
+			return "define"
+		}
+		return "assignment"
+	case *ast.ExprStmt:
+		if _, ok := s.X.(*ast.CallExpr); ok {
+			return "call"
+		}
+		return "expression"
+	case *ast.ReturnStmt:
+		return "return"
+	case *ast.IfStmt:
+		return "if"
+	case *ast.ForStmt, *ast.RangeStmt:
+		return "for"
+	case *ast.SwitchStmt, *ast.TypeSwitchStmt:
+		return "switch"
+	case *ast.SelectStmt:
+		return "select"
+	case *ast.DeclStmt:
+		return "declaration"
+	case *ast.DeferStmt:
+		return "defer"
+	case *ast.GoStmt:
+		return "go"
+	case *ast.BlockStmt:
+		return "block"
+	case *ast.LabeledStmt:
+		return "labeled"
+	}
+	return "statement"
+}
+
+// findStatement resolves the anchor or path of cfg to a single statement of
+// body. An anchor matching several statements is reported as ambiguous,
+// except that a statement containing another match is not counted.
+func findStatement(fset *token.FileSet, content []byte, body *ast.BlockStmt, symbol string, cfg StatementConfig) (ast.Stmt, *Error) {
+	var all []stmtMatch
+	walkStmts(body.List, "", func(m stmtMatch) { all = append(all, m) })
+
+	if cfg.Path != "" {
+		for _, m := range all {
+			if m.path == cfg.Path {
+				return m.stmt, nil
+			}
+		}
+		return nil, newError(CodeStatementNotFound, &ErrorDetails{Symbol: symbol}, "No statement at path %s in %s", cfg.Path, symbol)
+	}
+
+	anchor := strings.Join(strings.Fields(cfg.Anchor), " ")
+	var matches []stmtMatch
+	for _, m := range all {
+		text := content[fset.Position(m.stmt.Pos()).Offset:fset.Position(m.stmt.End()).Offset]
+		if !strings.Contains(strings.Join(strings.Fields(string(text)), " "), anchor) {
+			continue
+		}
+		// Walking parents first means an enclosing match is always the
+		// latest one whose path prefixes this one
+		for len(matches) > 0 && strings.HasPrefix(m.path, matches[len(matches)-1].path+".") {
+			matches = matches[:len(matches)-1]
+		}
+		matches = append(matches, m)
+	}
+
+	details := &ErrorDetails{Symbol: symbol}
+	for _, m := range matches {
+		details.Candidates = append(details.Candidates, declCandidate(fset, Candidate{Name: m.path, Kind: stmtKind(m.stmt)}, m.stmt))
+	}
+	switch len(matches) {
+	case 0:
+		return nil, newError(CodeStatementNotFound, details, "No statement in %s matches anchor %q", symbol, cfg.Anchor)
+	case 1:
+		return matches[0].stmt, nil
+	}
+	return nil, newError(CodeAmbiguousStatement, details,
+		"Anchor %q is ambiguous: %d statements in %s match (use a longer anchor or a statement path)", cfg.Anchor, len(matches), symbol)
+}
+
+// editStatement inserts next to, replaces or deletes one statement inside a
+// function body by splicing the source text
+func editStatement(fset *token.FileSet, file *ast.File, content []byte, req EditRequest) EditResult {
+	newContent := strings.Trim(req.Content, "\n")
+	if req.EditType != "delete-statement" {
+		if err := parseStatements(file.Name.Name, newContent); err != nil {
+			return failure(err)
+		}
+	}
+
+	decl, resolved, findErr := findTarget(fset, file, req.Symbol, req.FuzzyMatch)
+	if findErr != nil {
+		return failure(findErr)
+	}
+	fn, ok := decl.(*ast.FuncDecl)
+	if !ok || fn.Body == nil {
+		return failure(newError(CodeInvalidRequest, &ErrorDetails{Symbol: req.Symbol}, "Cannot edit statements of %s: not a function or method with a body", req.Symbol))
+	}
+	stmt, err := findStatement(fset, content, fn.Body, req.Symbol, *req.Statement)
+	if err != nil {
+		return failure(err)
+	}
+
+	// Work on whole lines, taking a trailing comment along, unless the
+	// statement shares its line with other code
+	start := fset.Position(stmt.Pos()).Offset
+	end := fset.Position(stmt.End()).Offset
+	lineStart := start
+	for lineStart > 0 && (content[lineStart-1] == ' ' || content[lineStart-1] == '\t') {
+		lineStart--
+	}
+	lineEnd := end
+	for _, c := range file.Comments {
+		if fset.Position(c.Pos()).Offset >= end && fset.Position(c.Pos()).Line == fset.Position(stmt.End()).Line {
+			lineEnd = fset.Position(c.End()).Offset
+		}
+	}
+	for lineEnd < len(content) && (content[lineEnd] == ' ' || content[lineEnd] == '\t') {
+		lineEnd++
+	}
+	if lineStart > 0 && content[lineStart-1] == '\n' && lineEnd < len(content) && content[lineEnd] == '\n' {
+		start, end = lineStart, lineEnd
+	}
+
+	var edited string
+	switch req.EditType {
+	case "insert-statement":
+		if req.Statement.Position == "before" {
+			edited = string(content[:start]) + newContent + "\n" + string(content[start:])
+		} else {
+			edited = string(content[:end]) + "\n" + newContent + string(content[end:])
+		}
+	case "replace-statement":
+		edited = string(content[:start]) + newContent + string(content[end:])
+	case "delete-statement":
+		if end < len(content) && content[end] == '\n' && start > 0 && content[start-1] == '\n' {
+			end++
+		}
+		edited = string(content[:start]) + string(content[end:])
+	}
+
+	formatted, fmtErr := format.Source([]byte(edited))
+	if fmtErr != nil {
+		return failure(newError(CodeFormatFailed, nil, "Failed to format modified code: %v", fmtErr))
+	}
+	return EditResult{
+		Success:        true,
+		Content:        string(formatted),
+		ResolvedSymbol: resolved,
+	}
+}
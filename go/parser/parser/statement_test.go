@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditStatements(t *testing.T) {
+	initial := `package test
+
+func Process(items []string) error {
+	count := 0
+	for _, item := range items {
+		if item == "" {
+			return nil
+		}
+		count++ // tally
+	}
+	log.Print(count)
+	return nil
+}
+
+func Declared() int
+`
+
+	tests := []struct {
+		name     string
+		editType string
+		cfg      StatementConfig
+		content  string
+		wantCode ErrorCode
+		want     string
+		wantNot  string
+	}{
+		{
+			name:     "insert guard before anchor",
+			editType: "insert-statement",
+			cfg:      StatementConfig{Anchor: "count := 0", Position: "before"},
+			content:  "if len(items) == 0 {\nreturn errEmpty\n}",
+			want:     "{\n\tif len(items) == 0 {\n\t\treturn errEmpty\n\t}\n\tcount := 0\n",
+		},
+		{
+			name:     "insert after statement with trailing comment",
+			editType: "insert-statement",
+			cfg:      StatementConfig{Anchor: "count++", Position: "after"},
+			content:  "log.Print(item)",
+			want:     "\t\tcount++ // tally\n\t\tlog.Print(item)\n\t}\n",
+		},
+		{
+			name:     "innermost match wins",
+			editType: "replace-statement",
+			cfg:      StatementConfig{Anchor: `item == ""`},
+			content:  "if item == \"-\" {\ncontinue\n}",
+			want:     "\t\tif item == \"-\" {\n\t\t\tcontinue\n\t\t}\n\t\tcount++",
+		},
+		{
+			name:     "anchor whitespace is normalized",
+			editType: "insert-statement",
+			cfg:      StatementConfig{Anchor: "for _, item :=\n   range items", Position: "before"},
+			content:  "defer log.Print(\"done\")",
+			want:     "\tcount := 0\n\tdefer log.Print(\"done\")\n\tfor _, item",
+		},
+		{
+			name:     "replace by path",
+			editType: "replace-statement",
+			cfg:      StatementConfig{Path: "2.1.1.1.1"},
+			content:  "return errEmpty",
+			want:     "\t\tif item == \"\" {\n\t\t\treturn errEmpty\n\t\t}\n",
+		},
+		{
+			name:     "delete with trailing comment",
+			editType: "delete-statement",
+			cfg:      StatementConfig{Path: "2.1.2"},
+			want:     "\t\t}\n\t}\n\tlog.Print(count)",
+			wantNot:  "tally",
+		},
+		{
+			name:     "ambiguous anchor",
+			editType: "delete-statement",
+			cfg:      StatementConfig{Anchor: "return nil"},
+			wantCode: CodeAmbiguousStatement,
+		},
+		{
+			name:     "missing anchor",
+			editType: "delete-statement",
+			cfg:      StatementConfig{Anchor: "panic("},
+			wantCode: CodeStatementNotFound,
+		},
+		{
+			name:     "missing path",
+			editType: "delete-statement",
+			cfg:      StatementConfig{Path: "9"},
+			wantCode: CodeStatementNotFound,
+		},
+		{
+			name:     "content closing the function",
+			editType: "replace-statement",
+			cfg:      StatementConfig{Anchor: "count := 0"},
+			content:  "}\nfunc Extra() {",
+			wantCode: CodeContentSyntax,
+		},
+		{
+			name:     "anchor and path together",
+			editType: "delete-statement",
+			cfg:      StatementConfig{Anchor: "count := 0", Path: "1"},
+			wantCode: CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			req := EditRequest{Path: "test.go", EditType: tt.editType, Symbol: "Process", Content: tt.content, Statement: &cfg}
+			result := EditSource(req, []byte(initial))
+			if result.Code != tt.wantCode {
+				t.Fatalf("EditSource() code = %q, want %q (error: %s)", result.Code, tt.wantCode, result.Error)
+			}
+			if !strings.Contains(result.Content, tt.want) {
+				t.Errorf("EditSource() content missing %q:\n%s", tt.want, result.Content)
+			}
+			if tt.wantNot != "" && strings.Contains(result.Content, tt.wantNot) {
+				t.Errorf("EditSource() content still contains %q:\n%s", tt.wantNot, result.Content)
+			}
+		})
+	}
+}
+
+func TestAmbiguousStatementCandidates(t *testing.T) {
+	src := "package test\n\nfunc F() {\n\tif a {\n\t\treturn\n\t}\n\treturn\n}\n"
+	req := EditRequest{Path: "test.go", EditType: "delete-statement", Symbol: "F", Statement: &StatementConfig{Anchor: "return"}}
+	result := EditSource(req, []byte(src))
+	if result.Code != CodeAmbiguousStatement || result.Details == nil {
+		t.Fatalf("EditSource() code = %q, want %q", result.Code, CodeAmbiguousStatement)
+	}
+	var got []string
+	for _, c := range result.Details.Candidates {
+		got = append(got, c.Name+":"+c.Kind)
+	}
+	if want := "1.1.1:return 2:return"; strings.Join(got, " ") != want {
+		t.Errorf("Candidates = %v, want %s", got, want)
+	}
+}
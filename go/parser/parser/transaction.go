@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileChange describes one file written by an operation that spans files
+type FileChange struct {
+	Path    string // File that was written
+	Content string // New content of the file
+	Diff    string // Unified diff against the previous content
+	Created bool   `json:",omitempty"` // Whether the file did not exist before
+}
+
+// fileWrite is a pending change to one file of a transaction
+type fileWrite struct {
+	path   string
+	before []byte // Content the change was computed from, nil when creating
+	after  []byte
+	create bool
+}
+
+// change describes w as a FileChange
+func (w fileWrite) change() FileChange {
+	return FileChange{
+		Path:    w.path,
+		Content: string(w.after),
+		Diff:    UnifiedDiff(w.path, string(w.before), string(w.after)),
+		Created: w.create,
+	}
+}
+
+// commitFiles writes every file or none. All new contents are staged next to
+// their targets before any target is replaced, and targets already replaced
+// are restored if a later one fails.
+func commitFiles(writes []fileWrite) *Error {
+	// Refuse to overwrite changes made by someone else since the files were read
+	for _, w := range writes {
+		current, err := os.ReadFile(w.path)
+		switch {
+		case w.create && err == nil:
+			return newError(CodeFileExists, nil, "File already exists: %s", w.path)
+		case w.create && errors.Is(err, fs.ErrNotExist):
+		case err != nil || !bytes.Equal(current, w.before):
+			logger.Warn("file changed while editing", "path", w.path)
+			return newError(CodeFileChanged, nil, "File changed while editing: %s", w.path)
+		}
+	}
+
+	staged := make([]string, 0, len(writes))
+	removeStaged := func() {
+		for _, tmp := range staged {
+			os.Remove(tmp)
+		}
+	}
+	for _, w := range writes {
+		tmp, err := stageFile(w)
+		if err != nil {
+			removeStaged()
+			return newError(CodeWriteFailed, nil, "Failed to write file: %v", err)
+		}
+		staged = append(staged, tmp)
+	}
+
+	for i, w := range writes {
+		if err := os.Rename(staged[i], w.path); err != nil {
+			for _, done := range writes[:i] {
+				if done.create {
+					os.Remove(done.path)
+				} else if restoreErr := os.WriteFile(done.path, done.before, 0644); restoreErr != nil {
+					logger.Error("failed to restore file", "path", done.path, "error", restoreErr)
+				}
+			}
+			removeStaged()
+			return newError(CodeWriteFailed, nil, "Failed to write file: %v", err)
+		}
+		logger.Debug("wrote file", "path", w.path, "bytes", len(w.after))
+	}
+	return nil
+}
+
+// stageFile writes the new content of w to a temporary file in the same
+// directory, keeping the permissions of the file it replaces
+func stageFile(w fileWrite) (string, error) {
+	mode := fs.FileMode(0644)
+	if info, err := os.Stat(w.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(w.path), "."+filepath.Base(w.path)+".*")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(w.after)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), mode)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -3,21 +3,37 @@ package parser
 // EditRequest represents a request to edit code
 type EditRequest struct {
 	Path     string        // File path to edit
-	EditType string        // Required: "replace", "insert", or "delete"
+	EditType string        // Required: "replace", "insert", "delete", "replace-body", "set-doc", "insert-statement", "replace-statement" or "delete-statement"
 	Symbol   string        // Symbol name to target (for replace/delete) or new symbol name (for insert)
-	Content  string        // New content to insert/replace
+	Content  string        // New content to insert/replace, or the doc comment text for set-doc (empty removes it)
 	Insert   *InsertConfig `json:",omitempty"` // Required configuration when EditType is "insert"
+	Doc      *DocConfig    `json:",omitempty"` // Optional configuration when EditType is "set-doc"
+
+	Statement *StatementConfig `json:",omitempty"` // Required configuration for statement edits inside the body of Symbol
+
+	AllowGenerated bool `json:",omitempty"` // Permit edits to files marked "Code generated ... DO NOT EDIT."
+	FuzzyMatch     bool `json:",omitempty"` // Edit the only close match when the target symbol is misspelled or miscased
+
+	CheckImplementations bool `json:",omitempty"` // Report package types that stop satisfying an edited interface
 }
 
 // InsertConfig contains the configuration for insert operations
 type InsertConfig struct {
-	Position         string // Required: "before" or "after"
-	RelativeToSymbol string // Required: Name of the existing symbol to insert relative to
+	Position         string // Required: "before", "after", "start", "after-imports", "end", "first-method-of:T" or "last-method-of:T"
+	RelativeToSymbol string // Name of the existing symbol to insert relative to; required for "before" and "after"
 }
 
 // EditResult represents the result of an edit operation
 type EditResult struct {
-	Success bool   // Whether the edit was successful
-	Error   string // Error message if unsuccessful
-	Content string // The edited content
+	Success bool          // Whether the edit was successful
+	Error   string        // Error message if unsuccessful
+	Code    ErrorCode     `json:",omitempty"` // Stable error code if unsuccessful
+	Details *ErrorDetails `json:",omitempty"` // Structured error details, such as candidate symbols
+	Content string        // The edited content
+
+	ResolvedSymbol string `json:",omitempty"` // Symbol actually edited when FuzzyMatch resolved a misspelled target
+
+	BrokenImplementations []string `json:",omitempty"` // Package types that satisfied the edited interface before but not after
+
+	UnresolvedImports []string `json:",omitempty"` // Package names used by the code that no import could be found for
 }
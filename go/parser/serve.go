@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rosen/go-parser/parser"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification when ID is absent
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error object of a failed JSON-RPC 2.0 request
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// rpcNullID identifies responses to requests whose ID could not be read
+var rpcNullID = json.RawMessage("null")
+
+// rpcWriter serialises messages from concurrent handlers onto one stream,
+// one JSON document per line
+type rpcWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newRPCWriter(w io.Writer) *rpcWriter {
+	return &rpcWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *rpcWriter) write(v interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(v)
+}
+
+// serve reads newline-delimited JSON-RPC 2.0 requests from r and writes
+// responses to w as they complete. The method names the Command operation
+// and the params carry the remaining Command fields. Requests run
+// concurrently, except that edits hold exclusive access to the file system
+// so that no parse observes a half-written file. A batch runs its requests
+// in order and is answered with an array. serve returns once r is exhausted
+// and every in-flight request has been answered.
+func serve(r io.Reader, w io.Writer) error {
+	out := newRPCWriter(w)
+	reader := bufio.NewReader(r)
+
+	var (
+		wg    sync.WaitGroup
+		files sync.RWMutex
+	)
+	defer wg.Wait()
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var msg json.RawMessage
+			if jsonErr := json.Unmarshal(line, &msg); jsonErr != nil {
+				out.write(rpcResponse{
+					JSONRPC: "2.0",
+					ID:      rpcNullID,
+					Error:   &rpcError{Code: rpcParseError, Message: fmt.Sprintf("invalid JSON: %v", jsonErr)},
+				})
+			} else if msg[0] == '[' {
+				var batch []json.RawMessage
+				json.Unmarshal(msg, &batch)
+				if len(batch) == 0 {
+					out.write(invalidRequest("invalid request: empty batch"))
+				} else {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						// A batch of notifications gets no response
+						if responses := handleBatch(batch, &files); len(responses) > 0 {
+							out.write(responses)
+						}
+					}()
+				}
+			} else if req, ok := decodeRequest(msg); !ok {
+				out.write(invalidRequest("invalid request: expected a request object"))
+			} else {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp, ok := handleRPC(req, &files)
+					if ok {
+						out.write(resp)
+					}
+				}()
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// decodeRequest reads a request object, reporting false for any other JSON
+// value and for objects whose fields have the wrong types
+func decodeRequest(msg json.RawMessage) (rpcRequest, bool) {
+	var req rpcRequest
+	if msg[0] != '{' || json.Unmarshal(msg, &req) != nil {
+		return req, false
+	}
+	return req, true
+}
+
+// invalidRequest answers a message that is valid JSON but not a request
+func invalidRequest(message string) rpcResponse {
+	return rpcResponse{JSONRPC: "2.0", ID: rpcNullID, Error: &rpcError{Code: rpcInvalidRequest, Message: message}}
+}
+
+// handleBatch executes the requests of a batch in order and returns the
+// responses to those that are not notifications
+func handleBatch(batch []json.RawMessage, files *sync.RWMutex) []rpcResponse {
+	var responses []rpcResponse
+	for _, msg := range batch {
+		req, ok := decodeRequest(msg)
+		if !ok {
+			responses = append(responses, invalidRequest("invalid request: expected a request object"))
+			continue
+		}
+		if resp, ok := handleRPC(req, files); ok {
+			responses = append(responses, resp)
+		}
+	}
+	return responses
+}
+
+// handleRPC executes a single request. It reports false for notifications,
+// which receive no response.
+func handleRPC(req rpcRequest, files *sync.RWMutex) (rpcResponse, bool) {
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	notification := len(req.ID) == 0
+	if notification {
+		resp.ID = rpcNullID
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &rpcError{Code: rpcInvalidRequest, Message: "invalid request: jsonrpc must be \"2.0\" and method is required"}
+		return resp, !notification
+	}
+
+	var cmd Command
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &cmd); err != nil {
+			resp.Error = &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+			return resp, !notification
+		}
+	}
+	cmd.Operation = req.Method
+
+	switch cmd.Operation {
+	case "edit", "rename", "create", "move":
+		files.Lock()
+		defer files.Unlock()
+	case "parse", "parse-package", "get", "skeleton", "context", "references":
+		files.RLock()
+		defer files.RUnlock()
+	default:
+		resp.Error = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method: %s", req.Method)}
+		return resp, !notification
+	}
+
+	start := time.Now()
+	result, err := execute(cmd)
+	if err != nil {
+		perr := codedError(parser.CodeInternal, err)
+		slog.Debug("request failed", "method", req.Method, "id", string(req.ID), "code", perr.Code, "duration", time.Since(start))
+		resp.Error = &rpcError{Code: rpcServerError, Message: perr.Message, Data: perr}
+	} else {
+		slog.Debug("request completed", "method", req.Method, "id", string(req.ID), "duration", time.Since(start))
+		resp.Result = result
+	}
+	return resp, !notification
+}
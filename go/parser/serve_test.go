@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rosen/go-parser/parser"
+)
+
+func TestServe(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "service.go")
+	content := `package test
+
+// Process handles data
+func Process() error {
+	return nil
+}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"parse","params":{"file":` + quote(path) + `}}`,
+		`{"jsonrpc":"2.0","id":"pkg","method":"parse-package","params":{"dir":` + quote(tmpDir) + `}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"parse","params":{"file":` + quote(filepath.Join(tmpDir, "missing.go")) + `}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"unknown"}`,
+		`{"jsonrpc":"2.0","method":"parse","params":{"file":` + quote(path) + `}}`,
+		`not json`,
+		``,
+	}
+
+	var out bytes.Buffer
+	if err := serve(strings.NewReader(strings.Join(requests, "\n")), &out); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	responses := make(map[string]rpcResponse)
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var resp struct {
+			rpcResponse
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("Response is not valid JSON: %q: %v", scanner.Text(), err)
+		}
+		if resp.JSONRPC != "2.0" {
+			t.Errorf("Response jsonrpc = %q, want 2.0", resp.JSONRPC)
+		}
+		resp.rpcResponse.Result = resp.Result
+		responses[string(resp.ID)] = resp.rpcResponse
+	}
+
+	// The notification gets no response, so only five lines are written
+	if len(responses) != 5 {
+		t.Fatalf("Got %d responses, want 5: %v", len(responses), responses)
+	}
+
+	if resp := responses["1"]; resp.Error != nil || !strings.Contains(string(resp.Result.(json.RawMessage)), `"Process"`) {
+		t.Errorf("parse response = %+v", resp)
+	}
+	if resp := responses[`"pkg"`]; resp.Error != nil || !strings.Contains(string(resp.Result.(json.RawMessage)), `"package":"test"`) {
+		t.Errorf("parse-package response = %+v", resp)
+	}
+	if resp := responses["3"]; resp.Error == nil || resp.Error.Code != rpcServerError {
+		t.Errorf("missing file response = %+v, want server error", resp)
+	} else if data, _ := resp.Error.Data.(map[string]interface{}); data["code"] != string(parser.CodeFileNotFound) {
+		t.Errorf("missing file error data = %v, want code %s", resp.Error.Data, parser.CodeFileNotFound)
+	}
+	if resp := responses["4"]; resp.Error == nil || resp.Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method response = %+v, want method not found", resp)
+	}
+	if resp := responses["null"]; resp.Error == nil || resp.Error.Code != rpcParseError {
+		t.Errorf("invalid JSON response = %+v, want parse error", resp)
+	}
+}
+
+func TestServeInvalidRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.go")
+	if err := os.WriteFile(path, []byte("package test\n\nfunc Process() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parse := `{"jsonrpc":"2.0","id":1,"method":"parse","params":{"file":` + quote(path) + `}}`
+
+	tests := []struct {
+		name    string
+		request string
+		want    string // Error codes or ids of the responses, "" for none
+	}{
+		{"syntax error", `{"jsonrpc":`, "-32700"},
+		{"number", `42`, "-32600"},
+		{"null", ` null`, "-32600"},
+		{"wrong field type", `{"jsonrpc":"2.0","id":1,"method":5}`, "-32600"},
+		{"empty batch", ` []`, "-32600"},
+		{"batch", `[` + parse + `, 7, {"jsonrpc":"2.0","method":"parse"}]`, "[1 -32600]"},
+		{"batch of notifications", `[{"jsonrpc":"2.0","method":"parse","params":{"file":` + quote(path) + `}}]`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := serve(strings.NewReader(tt.request+"\n"), &out); err != nil {
+				t.Fatalf("serve failed: %v", err)
+			}
+			// describe reduces a response to its error code, or its id on success
+			describe := func(resp rpcResponse) string {
+				if resp.Error != nil {
+					return strconv.Itoa(resp.Error.Code)
+				}
+				return string(resp.ID)
+			}
+			got := ""
+			if line := bytes.TrimSpace(out.Bytes()); len(line) > 0 && line[0] == '[' {
+				var batch []rpcResponse
+				if err := json.Unmarshal(line, &batch); err != nil {
+					t.Fatalf("Response is not valid JSON: %q: %v", line, err)
+				}
+				var codes []string
+				for _, resp := range batch {
+					codes = append(codes, describe(resp))
+				}
+				got = "[" + strings.Join(codes, " ") + "]"
+			} else if len(line) > 0 {
+				var resp rpcResponse
+				if err := json.Unmarshal(line, &resp); err != nil {
+					t.Fatalf("Response is not valid JSON: %q: %v", line, err)
+				}
+				got = describe(resp)
+			}
+			if got != tt.want {
+				t.Errorf("serve(%s) = %s, want %s (output %q)", tt.request, got, tt.want, out.String())
+			}
+		})
+	}
+}
+
+func TestServeEdit(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "service.go")
+	content := `package test
+
+func Old() {}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	edit := map[string]interface{}{
+		"edit": map[string]interface{}{
+			"Path":     path,
+			"EditType": "replace",
+			"Symbol":   "Old",
+			"Content":  "func New() {}",
+		},
+	}
+	params, err := json.Marshal(edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	req := `{"jsonrpc":"2.0","id":7,"method":"edit","params":` + string(params) + "}\n"
+	if err := serve(strings.NewReader(req), &out); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	var resp rpcResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("Response is not valid JSON: %q: %v", out.String(), err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("edit failed: %+v", resp.Error)
+	}
+	result, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(result), "func New()") {
+		t.Errorf("File not edited:\n%s", result)
+	}
+}
+
+// quote returns s as a JSON string literal
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}